@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+// AtomicCache is implemented by caches with atomic counters and locks, for rate limiters and
+// deduplication. RedisCache and BadgerCache implement it
+type AtomicCache interface {
+	Cache
+	// Increment adds by to the counter at key and returns the new value. A missing key
+	// counts from zero
+	Increment(key string, by int64) (int64, error)
+	// Decrement subtracts by from the counter at key and returns the new value
+	Decrement(key string, by int64) (int64, error)
+	// Add stores value at key only if the key is not already set, and reports whether it did
+	Add(key string, value interface{}, expires ...int) (bool, error)
+	// Lock takes the lock called key for ttl, or returns ErrLockNotAcquired
+	Lock(key string, ttl time.Duration) (*Lock, error)
+}
+
+// ErrNotCounter is returned when incrementing a key that holds something other than a number
+var ErrNotCounter = errors.New("cache: value is not a counter")
+
+// Increment adds by to the counter at key with INCRBY. Counters are stored as plain numbers
+// rather than encoded, so only keys written by Increment, Decrement and Add of an integer
+// can be counted
+func (c *RedisCache) Increment(key string, by int64) (int64, error) {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	n, err := redis.Int64(conn.Do("INCRBY", c.key(key), by))
+	if err != nil {
+		if _, ok := err.(redis.Error); ok {
+			return 0, ErrNotCounter
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+func (c *RedisCache) Decrement(key string, by int64) (int64, error) {
+	return c.Increment(key, -by)
+}
+
+// Add stores value at key with SET NX. Integers are stored as plain numbers, like counters,
+// so Add(key, 0, ttl) starts a counter that expires, for rate limiters
+func (c *RedisCache) Add(key string, value interface{}, expires ...int) (bool, error) {
+	var encoded []byte
+	if n, ok := integer(value); ok {
+		encoded = []byte(strconv.FormatInt(n, 10))
+	} else {
+		var err error
+		encoded, err = c.Serializer.encode(value)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	args := []interface{}{c.key(key), encoded, "NX"}
+	if len(expires) > 0 && expires[0] > 0 {
+		args = append(args, "PX", int64(expires[0])*1000)
+	}
+
+	_, err := redis.String(conn.Do("SET", args...))
+	if err == redis.ErrNil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *RedisCache) Lock(key string, ttl time.Duration) (*Lock, error) {
+	return acquire(c, key, ttl)
+}
+
+// parseCounter reads a counter written by INCRBY
+func parseCounter(data []byte) (int64, bool) {
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	return n, err == nil
+}
+
+// Increment adds by to the counter at key in a transaction, keeping the key's expiry.
+// Counters are stored encoded, so they can also be read with Get
+func (b BadgerCache) Increment(key string, by int64) (int64, error) {
+	var n int64
+	err := b.update(func(txn *badger.Txn) error {
+		n = 0
+		var expiresAt uint64
+
+		item, err := txn.Get([]byte(key))
+		if err == nil {
+			raw, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			value, err := b.Serializer.decode(raw)
+			if err != nil {
+				return err
+			}
+
+			n, err = toInt64(value)
+			if err != nil {
+				return err
+			}
+			expiresAt = item.ExpiresAt()
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		n += by
+		encoded, err := b.Serializer.encode(n)
+		if err != nil {
+			return err
+		}
+
+		e := badger.NewEntry([]byte(key), encoded)
+		e.ExpiresAt = expiresAt
+		return txn.SetEntry(e)
+	})
+
+	return n, err
+}
+
+func (b BadgerCache) Decrement(key string, by int64) (int64, error) {
+	return b.Increment(key, -by)
+}
+
+// Add stores value at key in a transaction that fails if the key is set
+func (b BadgerCache) Add(key string, value interface{}, expires ...int) (bool, error) {
+	encoded, err := b.Serializer.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	err = b.update(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		if err == nil {
+			return errLocked
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		e := badger.NewEntry([]byte(key), encoded)
+		if len(expires) > 0 && expires[0] > 0 {
+			e = e.WithTTL(time.Second * time.Duration(expires[0]))
+		}
+		return txn.SetEntry(e)
+	})
+
+	if err == errLocked {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b BadgerCache) Lock(key string, ttl time.Duration) (*Lock, error) {
+	return acquire(b, key, ttl)
+}
+
+// updateAttempts is how many times update runs a transaction that keeps conflicting
+const updateAttempts = 10
+
+// update runs fn in a read-write transaction, and runs it again when it conflicts with a
+// transaction that committed first. badger.ErrConflict is returned once every attempt conflicted
+func (b BadgerCache) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for i := 0; i < updateAttempts; i++ {
+		err = b.Conn.Update(fn)
+		if err != badger.ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+// integer converts the integer types to int64. Unlike toInt64 it leaves floats alone, so they
+// are still read back as floats
+func integer(v interface{}) (int64, bool) {
+	switch v.(type) {
+	case float32, float64:
+		return 0, false
+	}
+	n, err := toInt64(v)
+	return n, err == nil
+}
+
+// toInt64 converts the integer types a codec can decode a counter into
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %T", ErrNotCounter, v)
+}
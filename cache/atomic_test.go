@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestAtomicCache_Increment(t *testing.T) {
+	for name, c := range map[string]AtomicCache{"redis": &testRedisCache, "badger": testBadgerCache} {
+		_ = c.Forget("counter")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := c.Increment("counter", 2); err != nil {
+					t.Errorf("%s: %s", name, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		n, err := c.Decrement("counter", 5)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if n != 15 {
+			t.Errorf("%s: expected 15; got %d", name, n)
+		}
+
+		v, err := c.Get("counter")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if v != int64(15) {
+			t.Errorf("%s: expected Get to return 15; got %v", name, v)
+		}
+
+		_ = c.Set("not-counter", "bar")
+		_, err = c.Increment("not-counter", 1)
+		if err == nil {
+			t.Errorf("%s: incremented a value that is not a counter", name)
+		}
+	}
+}
+
+func TestAtomicCache_Add(t *testing.T) {
+	for name, c := range map[string]AtomicCache{"redis": &testRedisCache, "badger": testBadgerCache} {
+		_ = c.Forget("add")
+
+		added, err := c.Add("add", "first", 60)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if !added {
+			t.Errorf("%s: value was not added to a missing key", name)
+		}
+
+		added, _ = c.Add("add", "second")
+		if added {
+			t.Errorf("%s: value was added to a key that is set", name)
+		}
+
+		v, _ := c.Get("add")
+		if v != "first" {
+			t.Errorf("%s: expected first; got %v", name, v)
+		}
+	}
+}
+
+func TestAtomicCache_Lock(t *testing.T) {
+	for name, c := range map[string]AtomicCache{"redis": &testRedisCache, "badger": testBadgerCache} {
+		lock, err := c.Lock("job", time.Minute)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		_, err = c.Lock("job", time.Minute)
+		if err != ErrLockNotAcquired {
+			t.Errorf("%s: expected ErrLockNotAcquired; got %v", name, err)
+		}
+
+		if err := lock.Renew(2 * time.Minute); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		if err := lock.Release(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		if err := lock.Renew(time.Minute); err != ErrLockLost {
+			t.Errorf("%s: expected ErrLockLost after release; got %v", name, err)
+		}
+
+		lock, err = c.Lock("job", time.Minute)
+		if err != nil {
+			t.Errorf("%s: lock was not free after release: %v", name, err)
+		} else {
+			_ = lock.Release()
+		}
+	}
+}
+
+func TestAtomicCache_AddThenIncrement(t *testing.T) {
+	for name, c := range map[string]AtomicCache{"redis": &testRedisCache, "badger": testBadgerCache} {
+		_ = c.Forget("limit")
+
+		// a rate limiter starts the counter with its window, then counts within it
+		added, err := c.Add("limit", 0, 60)
+		if err != nil || !added {
+			t.Errorf("%s: expected the counter to be added; got %v, %v", name, added, err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := c.Increment("limit", 1); err != nil {
+				t.Errorf("%s: %s", name, err)
+			}
+		}
+
+		v, err := c.Get("limit")
+		if err != nil || v != int64(3) {
+			t.Errorf("%s: expected 3; got %v, %v", name, v, err)
+		}
+	}
+
+	// the window set by Add survives the increments
+	conn := testRedisCache.Conn.Get()
+	defer conn.Close()
+	ttl, _ := redis.Int(conn.Do("TTL", testRedisCache.key("limit")))
+	if ttl <= 0 || ttl > 60 {
+		t.Errorf("redis: expected the counter to expire within 60s; got %d", ttl)
+	}
+
+	err := testBadgerCache.Conn.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("limit"))
+		if err != nil {
+			return err
+		}
+		if item.ExpiresAt() == 0 {
+			t.Error("badger: expected the counter to expire")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// floats are not counters, and keep their type
+	_ = testRedisCache.Forget("ratio")
+	_, _ = testRedisCache.Add("ratio", 0.5)
+	if v, _ := testRedisCache.Get("ratio"); v != 0.5 {
+		t.Errorf("redis: expected 0.5; got %v", v)
+	}
+}
+
+func TestBadgerCache_UpdateConflicts(t *testing.T) {
+	var attempts int
+	err := testBadgerCache.update(func(txn *badger.Txn) error {
+		attempts++
+		return badger.ErrConflict
+	})
+
+	if err != badger.ErrConflict || attempts != updateAttempts {
+		t.Errorf("expected badger.ErrConflict after %d attempts; got %v after %d", updateAttempts, err, attempts)
+	}
+
+	attempts = 0
+	err = testBadgerCache.update(func(txn *badger.Txn) error {
+		if attempts++; attempts < 3 {
+			return badger.ErrConflict
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("expected a conflicting transaction to be run again; got %v after %d attempts", err, attempts)
+	}
+}
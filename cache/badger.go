@@ -1,13 +1,15 @@
 package cache
 
 import (
+	"fmt"
 	"github.com/dgraph-io/badger/v3"
 	"time"
 )
 
 type BadgerCache struct {
-	Conn   *badger.DB
-	Prefix string
+	Conn       *badger.DB
+	Prefix     string
+	Serializer *Serializer
 }
 
 func (b BadgerCache) Has(key string) (bool, error) {
@@ -38,23 +40,18 @@ func (b BadgerCache) Get(key string) (interface{}, error) {
 		return nil
 	})
 
-	if err != nil {
-		return nil, err
-	}
-
-	decoded, err := decode(string(fromCache))
-	if err != nil {
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
-	return decoded[key], nil
+	return b.Serializer.decode(fromCache)
 }
 
 func (b BadgerCache) Set(key string, value interface{}, expires ...int) error {
 
-	entry := Entry{}
-	entry[key] = value
-	encoded, err := encode(entry)
+	encoded, err := b.Serializer.encode(value)
 	if err != nil {
 		return err
 	}
@@ -73,7 +70,7 @@ func (b BadgerCache) Set(key string, value interface{}, expires ...int) error {
 		})
 	}
 
-	return nil
+	return err
 }
 
 func (b BadgerCache) Forget(key string) error {
@@ -139,3 +136,27 @@ func (b *BadgerCache) emptyByMatch(pattern string) error {
 	return err
 
 }
+
+// Remember returns the value at key, or loads it with loader and stores it for ttl seconds.
+// Concurrent calls for the same key share one loader call
+func (b BadgerCache) Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	return remember(b, b, fmt.Sprintf("%p:%s", b.Conn, key), key, ttl, loader, opts...)
+}
+
+// Count returns the number of keys in the database. It iterates every key, so it is meant
+// for tools and statistics rather than requests
+func (b BadgerCache) Count() (int, error) {
+	n := 0
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+// BatchCache is implemented by caches that can read and write many keys in one round trip.
+// RedisCache and BadgerCache implement it; GetMany, SetMany and ForgetMany work with any Cache
+type BatchCache interface {
+	Cache
+	// GetMany returns the value of every key found, keyed by key. Missing keys are left out
+	GetMany(keys ...string) (map[string]interface{}, error)
+	SetMany(items map[string]interface{}, expires ...int) error
+	ForgetMany(keys ...string) error
+}
+
+// the most keys sent in a single DEL
+const deleteBatchSize = 500
+
+// GetMany calls c.GetMany when c implements BatchCache, and otherwise gets each key in turn
+func GetMany(c Cache, keys ...string) (map[string]interface{}, error) {
+	if bc, ok := c.(BatchCache); ok {
+		return bc.GetMany(keys...)
+	}
+
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value, err := c.Get(key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// SetMany calls c.SetMany when c implements BatchCache, and otherwise sets each key in turn
+func SetMany(c Cache, items map[string]interface{}, expires ...int) error {
+	if bc, ok := c.(BatchCache); ok {
+		return bc.SetMany(items, expires...)
+	}
+
+	for key, value := range items {
+		if err := c.Set(key, value, expires...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForgetMany calls c.ForgetMany when c implements BatchCache, and otherwise forgets each key
+// in turn
+func ForgetMany(c Cache, keys ...string) error {
+	if bc, ok := c.(BatchCache); ok {
+		return bc.ForgetMany(keys...)
+	}
+
+	for _, key := range keys {
+		if err := c.Forget(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMany reads every key with a single MGET
+func (c *RedisCache) GetMany(keys ...string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = c.key(key)
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	replies, err := redis.ByteSlices(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, reply := range replies {
+		if reply == nil {
+			continue
+		}
+
+		if n, ok := parseCounter(reply); ok {
+			values[keys[i]] = n
+			continue
+		}
+
+		value, err := c.Serializer.decode(reply)
+		if err != nil {
+			return nil, err
+		}
+		values[keys[i]] = value
+	}
+
+	return values, nil
+}
+
+// SetMany writes every item in a single pipeline
+func (c *RedisCache) SetMany(items map[string]interface{}, expires ...int) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	encoded := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, err := c.Serializer.encode(value)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	for key, data := range encoded {
+		var err error
+		if len(expires) > 0 {
+			err = conn.Send("SETEX", c.key(key), expires[0], data)
+		} else {
+			err = conn.Send("SET", c.key(key), data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// flushes the pipeline and returns every reply. An error reply, such as the one for an
+	// invalid expiry, is one of the replies rather than the error Do returns
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForgetMany deletes every key, a batch of keys per DEL
+func (c *RedisCache) ForgetMany(keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	return c.deleteKeys(prefixed)
+}
+
+// deleteKeys deletes keys that already carry the prefix, deleteBatchSize keys per DEL
+func (c *RedisCache) deleteKeys(keys []string) error {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		args := make([]interface{}, 0, end-start)
+		for _, key := range keys[start:end] {
+			args = append(args, key)
+		}
+
+		if _, err := conn.Do("DEL", args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMany reads every key in a single transaction
+func (b BadgerCache) GetMany(keys ...string) (map[string]interface{}, error) {
+	raw := make(map[string][]byte, len(keys))
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			item, err := txn.Get([]byte(key))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			raw[key], err = item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(raw))
+	for key, data := range raw {
+		value, err := b.Serializer.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// SetMany writes every item in a single transaction, so either all or none are stored
+func (b BadgerCache) SetMany(items map[string]interface{}, expires ...int) error {
+	entries := make([]*badger.Entry, 0, len(items))
+	for key, value := range items {
+		encoded, err := b.Serializer.encode(value)
+		if err != nil {
+			return err
+		}
+
+		e := badger.NewEntry([]byte(key), encoded)
+		if len(expires) > 0 {
+			e = e.WithTTL(time.Second * time.Duration(expires[0]))
+		}
+		entries = append(entries, e)
+	}
+
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		for _, e := range entries {
+			if err := txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ForgetMany deletes every key in a single transaction
+func (b BadgerCache) ForgetMany(keys ...string) error {
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
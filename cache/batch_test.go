@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestBatchCache(t *testing.T) {
+	caches := map[string]Cache{
+		"redis":  &testRedisCache,
+		"badger": testBadgerCache,
+		"memory": NewMemoryCache(0, 0),
+	}
+
+	for name, c := range caches {
+		err := SetMany(c, map[string]interface{}{"many:1": "one", "many:2": "two", "many:3": "three"}, 60)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		values, err := GetMany(c, "many:1", "many:2", "many:3", "many:missing")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		if len(values) != 3 || values["many:1"] != "one" || values["many:3"] != "three" {
+			t.Errorf("%s: unexpected values %v", name, values)
+		}
+
+		if _, ok := values["many:missing"]; ok {
+			t.Errorf("%s: missing key was returned", name)
+		}
+
+		err = ForgetMany(c, "many:1", "many:2")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		values, _ = GetMany(c, "many:1", "many:2", "many:3")
+		if len(values) != 1 || values["many:3"] != "three" {
+			t.Errorf("%s: expected only many:3 to be left; got %v", name, values)
+		}
+	}
+}
+
+func TestRedisCache_EmptyByMatchBatches(t *testing.T) {
+	items := make(map[string]interface{})
+	for i := 0; i < deleteBatchSize*2+10; i++ {
+		items[fmt.Sprintf("batch:%d", i)] = i
+	}
+
+	err := testRedisCache.SetMany(items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testRedisCache.EmptyByMatch("batch:")
+	if err != nil {
+		t.Error(err)
+	}
+
+	keys, _ := testRedisCache.getKeys(testRedisCache.key("batch:*"))
+	if len(keys) != 0 {
+		t.Errorf("expected every batch key to be deleted; %d left", len(keys))
+	}
+}
+
+func TestRedisCache_SetManyErrorReply(t *testing.T) {
+	err := testRedisCache.SetMany(map[string]interface{}{"many:invalid": "value"}, 0)
+
+	var reply redis.Error
+	if !errors.As(err, &reply) || !strings.Contains(err.Error(), "invalid expire time") {
+		t.Errorf("expected the error reply of SETEX; got %v", err)
+	}
+}
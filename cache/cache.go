@@ -3,8 +3,13 @@ package cache
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 )
 
+// ErrNotFound is returned by Get when a key is not in the cache or has expired. Every driver
+// returns it, so callers can tell a miss from a failure without knowing which driver is in use
+var ErrNotFound = errors.New("cache: key not found")
+
 type Cache interface {
 	Has(string) (bool, error)
 	Get(string) (interface{}, error)
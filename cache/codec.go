@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec converts cache values to and from bytes. ID is written in front of every value so
+// entries can be decoded after an instance switches to another codec; it must be unique
+// among registered codecs
+type Codec interface {
+	ID() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Compressor compresses encoded values. ID is written in front of every value, like a
+// Codec's; zero means the value is not compressed
+type Compressor interface {
+	ID() byte
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// the first byte of every value written by a Serializer. A gob stream never starts with it,
+// so values written before codecs existed are still recognised and decoded as gob
+const formatMarker byte = 0xCE
+
+// DefaultCompressThreshold is used when a Serializer has a Compressor but no Threshold
+const DefaultCompressThreshold = 1024
+
+var (
+	GobCodec     Codec = gobCodec{}
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+
+	ZstdCompressor   Compressor = &zstdCompressor{}
+	SnappyCompressor Compressor = snappyCompressor{}
+)
+
+var (
+	registryMu  sync.RWMutex
+	codecs      = map[byte]Codec{}
+	compressors = map[byte]Compressor{}
+)
+
+func init() {
+	RegisterCodec(GobCodec)
+	RegisterCodec(JSONCodec)
+	RegisterCodec(MsgpackCodec)
+	RegisterCompressor(ZstdCompressor)
+	RegisterCompressor(SnappyCompressor)
+}
+
+// RegisterCodec makes c available for decoding. Codecs set on a Serializer do not need to be
+// registered to be written, but must be registered to be read back
+func RegisterCodec(c Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	codecs[c.ID()] = c
+}
+
+// RegisterCompressor makes c available for decompressing values
+func RegisterCompressor(c Compressor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	compressors[c.ID()] = c
+}
+
+// CodecByName returns the built in codec called gob, json or msgpack
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case "", "gob":
+		return GobCodec, nil
+	case "json":
+		return JSONCodec, nil
+	case "msgpack":
+		return MsgpackCodec, nil
+	}
+	return nil, fmt.Errorf("cache: unknown codec %q", name)
+}
+
+// CompressorByName returns the built in compressor called zstd or snappy, and nil for none
+func CompressorByName(name string) (Compressor, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "zstd":
+		return ZstdCompressor, nil
+	case "snappy":
+		return SnappyCompressor, nil
+	}
+	return nil, fmt.Errorf("cache: unknown compressor %q", name)
+}
+
+// Serializer turns values into the bytes a driver stores. The zero value, and a nil
+// *Serializer, encode with gob and never compress
+type Serializer struct {
+	Codec      Codec
+	Compressor Compressor
+	// values larger than Threshold bytes after encoding are compressed
+	Threshold int
+}
+
+// serializerOf returns the Serializer c stores its values with
+func serializerOf(c Cache) *Serializer {
+	switch c := c.(type) {
+	case *RedisCache:
+		return c.Serializer
+	case *BadgerCache:
+		return c.Serializer
+	case *DatabaseCache:
+		return c.Serializer
+	case *MemoryCache:
+		return c.Serializer
+	case *TieredCache:
+		return serializerOf(c.L2)
+	}
+	return nil
+}
+
+func (s *Serializer) codec() Codec {
+	if s == nil || s.Codec == nil {
+		return GobCodec
+	}
+	return s.Codec
+}
+
+func (s *Serializer) encode(value interface{}) ([]byte, error) {
+	codec := s.codec()
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var compression byte
+	if s != nil && s.Compressor != nil {
+		threshold := s.Threshold
+		if threshold <= 0 {
+			threshold = DefaultCompressThreshold
+		}
+
+		if len(data) > threshold {
+			data, err = s.Compressor.Compress(data)
+			if err != nil {
+				return nil, err
+			}
+			compression = s.Compressor.ID()
+		}
+	}
+
+	return append([]byte{formatMarker, codec.ID(), compression}, data...), nil
+}
+
+func (s *Serializer) decode(data []byte) (interface{}, error) {
+	// written before codecs existed: a gob encoded Entry holding a single value
+	if len(data) == 0 || data[0] != formatMarker {
+		return gobCodec{}.value(data)
+	}
+
+	if len(data) < 3 {
+		return nil, errors.New("cache: value is too short")
+	}
+
+	registryMu.RLock()
+	codec, ok := codecs[data[1]]
+	compressor := compressors[data[2]]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("cache: no codec registered with id %d", data[1])
+	}
+
+	payload := data[3:]
+	if data[2] != 0 {
+		if compressor == nil {
+			return nil, fmt.Errorf("cache: no compressor registered with id %d", data[2])
+		}
+
+		var err error
+		payload, err = compressor.Decompress(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var value interface{}
+	if err := codec.Unmarshal(payload, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// gobCodec stores the value in an Entry, exactly as values were stored before codecs
+// existed. Concrete types held in interfaces must be registered with gob.Register
+type gobCodec struct{}
+
+func (gobCodec) ID() byte { return 1 }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	return encode(Entry{"value": v})
+}
+
+func (c gobCodec) Unmarshal(data []byte, v interface{}) error {
+	value, err := c.value(data)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("cache: Unmarshal needs a non-nil pointer")
+	}
+
+	if value == nil {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	if !val.Type().AssignableTo(rv.Elem().Type()) {
+		return fmt.Errorf("cache: cannot decode %T into %s", value, rv.Elem().Type())
+	}
+	rv.Elem().Set(val)
+
+	return nil
+}
+
+// value returns the single value held in a gob encoded Entry, whatever its key
+func (gobCodec) value(data []byte) (interface{}, error) {
+	entry, err := decode(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range entry {
+		return value, nil
+	}
+	return nil, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte { return 2 }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ID() byte { return 3 }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// zstdCompressor shares one encoder and decoder, which are safe for concurrent use
+type zstdCompressor struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+func (*zstdCompressor) ID() byte { return 1 }
+
+func (z *zstdCompressor) init() error {
+	z.once.Do(func() {
+		z.encoder, z.err = zstd.NewWriter(nil)
+		if z.err != nil {
+			return
+		}
+		z.decoder, z.err = zstd.NewReader(nil)
+	})
+	return z.err
+}
+
+func (z *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	if err := z.init(); err != nil {
+		return nil, err
+	}
+	return z.encoder.EncodeAll(data, nil), nil
+}
+
+func (z *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	if err := z.init(); err != nil {
+		return nil, err
+	}
+	return z.decoder.DecodeAll(data, nil)
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) ID() byte { return 2 }
+
+func (snappyCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCompressor) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
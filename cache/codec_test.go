@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSerializer_Codecs(t *testing.T) {
+	for _, codec := range []Codec{GobCodec, JSONCodec, MsgpackCodec} {
+		s := &Serializer{Codec: codec}
+
+		data, err := s.encode("bar")
+		if err != nil {
+			t.Error(err)
+		}
+
+		if data[0] != formatMarker || data[1] != codec.ID() || data[2] != 0 {
+			t.Errorf("codec %d: unexpected header %v", codec.ID(), data[:3])
+		}
+
+		// a serializer configured with another codec must still read the value
+		out, err := (&Serializer{}).decode(data)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if out != "bar" {
+			t.Errorf("codec %d: expected bar; got %v", codec.ID(), out)
+		}
+	}
+}
+
+func TestSerializer_Compression(t *testing.T) {
+	long := strings.Repeat("compress me ", 200)
+
+	for _, compressor := range []Compressor{ZstdCompressor, SnappyCompressor} {
+		s := &Serializer{Codec: JSONCodec, Compressor: compressor, Threshold: 100}
+
+		data, err := s.encode(long)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if data[2] != compressor.ID() {
+			t.Errorf("compressor %d: value was not compressed", compressor.ID())
+		}
+
+		if len(data) >= len(long) {
+			t.Errorf("compressor %d: compressed value is not smaller", compressor.ID())
+		}
+
+		out, err := s.decode(data)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if out != long {
+			t.Errorf("compressor %d: value did not survive compression", compressor.ID())
+		}
+
+		// values under the threshold are stored as they are
+		data, _ = s.encode("short")
+		if data[2] != 0 {
+			t.Errorf("compressor %d: short value was compressed", compressor.ID())
+		}
+	}
+}
+
+func TestSerializer_Legacy(t *testing.T) {
+	// values written before codecs existed are a gob Entry keyed by the cache key
+	legacy, err := encode(Entry{"test-ug:foo": "bar"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	s := &Serializer{Codec: MsgpackCodec, Compressor: ZstdCompressor}
+	out, err := s.decode(legacy)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if out != "bar" {
+		t.Errorf("expected bar; got %v", out)
+	}
+}
+
+func TestSerializer_Drivers(t *testing.T) {
+	serializer := &Serializer{Codec: JSONCodec, Compressor: SnappyCompressor, Threshold: 10}
+
+	redisCache := testRedisCache
+	redisCache.Serializer = serializer
+	badgerCache := testBadgerCache
+	badgerCache.Serializer = serializer
+
+	for name, c := range map[string]Cache{"redis": &redisCache, "badger": badgerCache} {
+		err := c.Set("codec", "a value long enough to be compressed")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		out, err := c.Get("codec")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		if out != "a value long enough to be compressed" {
+			t.Errorf("%s: unexpected value %v", name, out)
+		}
+	}
+
+	// json values can be read without the cache package
+	conn := testRedisCache.Conn.Get()
+	defer conn.Close()
+
+	redisCache.Serializer = &Serializer{Codec: JSONCodec}
+	_ = redisCache.Set("plain", map[string]interface{}{"id": 1})
+
+	raw, err := conn.Do("GET", testRedisCache.key("plain"))
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.HasSuffix(raw.([]byte), []byte(`{"id":1}`)) {
+		t.Errorf("expected json after the header; got %q", raw)
+	}
+}
+
+func TestTyped_JSONCodec(t *testing.T) {
+	redisCache := testRedisCache
+	redisCache.Serializer = &Serializer{Codec: JSONCodec}
+
+	users := NewTyped[typedUser](&redisCache)
+	err := users.Set("typed-json", typedUser{ID: 3, Name: "Linus"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	got, found, err := users.Get("typed-json")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !found || got.ID != 3 || got.Name != "Linus" {
+		t.Errorf("expected Linus; got %v", got)
+	}
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DatabaseCache stores entries in a table of the application database, so applications that
+// already have postgres, mysql or sqlite do not need redis just to cache. The table is created
+// with the ugo make cache-table command. Expiry is stored as a unix timestamp and is NULL for
+// entries that never expire
+type DatabaseCache struct {
+	Conn       *sql.DB
+	DataType   string
+	Table      string
+	Serializer *Serializer
+}
+
+func (d *DatabaseCache) table() string {
+	if d.Table == "" {
+		return "cache"
+	}
+	return d.Table
+}
+
+// query rewrites ? placeholders to $1, $2... for postgres
+func (d *DatabaseCache) query(q string) string {
+	q = strings.ReplaceAll(q, "{table}", d.table())
+
+	switch d.DataType {
+	case "postgres", "postgresql", "pgx":
+		var b strings.Builder
+		n := 0
+		for _, r := range q {
+			if r == '?' {
+				n++
+				fmt.Fprintf(&b, "$%d", n)
+				continue
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	default:
+		return q
+	}
+}
+
+func (d *DatabaseCache) Has(key string) (bool, error) {
+	var found int
+	err := d.Conn.QueryRow(
+		d.query("SELECT 1 FROM {table} WHERE cache_key = ? AND (expiry IS NULL OR expiry > ?)"),
+		key, time.Now().Unix(),
+	).Scan(&found)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *DatabaseCache) Get(key string) (interface{}, error) {
+	var fromCache []byte
+	err := d.Conn.QueryRow(
+		d.query("SELECT value FROM {table} WHERE cache_key = ? AND (expiry IS NULL OR expiry > ?)"),
+		key, time.Now().Unix(),
+	).Scan(&fromCache)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return d.Serializer.decode(fromCache)
+}
+
+func (d *DatabaseCache) Set(key string, value interface{}, expires ...int) error {
+	encoded, err := d.Serializer.encode(value)
+	if err != nil {
+		return err
+	}
+
+	var expiry sql.NullInt64
+	if len(expires) > 0 && expires[0] > 0 {
+		expiry = sql.NullInt64{Int64: time.Now().Add(time.Second * time.Duration(expires[0])).Unix(), Valid: true}
+	}
+
+	_, err = d.Conn.Exec(d.upsert(), key, encoded, expiry)
+	return err
+}
+
+// upsert is the statement Set uses to insert an entry or replace the one at its key
+func (d *DatabaseCache) upsert() string {
+	switch d.DataType {
+	case "mysql", "mariadb":
+		return d.query("INSERT INTO {table} (cache_key, value, expiry) VALUES (?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE value = VALUES(value), expiry = VALUES(expiry)")
+	default:
+		return d.query("INSERT INTO {table} (cache_key, value, expiry) VALUES (?, ?, ?) " +
+			"ON CONFLICT (cache_key) DO UPDATE SET value = excluded.value, expiry = excluded.expiry")
+	}
+}
+
+func (d *DatabaseCache) Forget(key string) error {
+	_, err := d.Conn.Exec(d.query("DELETE FROM {table} WHERE cache_key = ?"), key)
+	return err
+}
+
+// EmptyByMatch removes every entry whose key starts with prefix
+func (d *DatabaseCache) EmptyByMatch(prefix string) error {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix)
+	_, err := d.Conn.Exec(d.query("DELETE FROM {table} WHERE cache_key LIKE ? ESCAPE '!'"), escaped+"%")
+	return err
+}
+
+func (d *DatabaseCache) Empty() error {
+	_, err := d.Conn.Exec(d.query("DELETE FROM {table}"))
+	return err
+}
+
+// DeleteExpired removes every expired row. Expired rows are never returned, but they stay
+// in the table until they are overwritten or removed here
+func (d *DatabaseCache) DeleteExpired() error {
+	_, err := d.Conn.Exec(d.query("DELETE FROM {table} WHERE expiry IS NOT NULL AND expiry <= ?"), time.Now().Unix())
+	return err
+}
+
+// Remember returns the value at key, or loads it with loader and stores it for ttl seconds.
+// Concurrent calls for the same key in this process share one loader call
+func (d *DatabaseCache) Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	return remember(d, nil, fmt.Sprintf("%p:%s:%s", d.Conn, d.table(), key), key, ttl, loader, opts...)
+}
+
+// Count returns the number of entries that have not expired
+func (d *DatabaseCache) Count() (int, error) {
+	var n int
+	err := d.Conn.QueryRow(
+		d.query("SELECT count(*) FROM {table} WHERE expiry IS NULL OR expiry > ?"),
+		time.Now().Unix(),
+	).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabaseCache_Has(t *testing.T) {
+	err := testDatabaseCache.Forget("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, err := testDatabaseCache.Has("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if inCache {
+		t.Error("foo found in cache and it shouldn`t be there!")
+	}
+
+	_ = testDatabaseCache.Set("foo", "bar")
+
+	inCache, err = testDatabaseCache.Has("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !inCache {
+		t.Error("foo not found in cache")
+	}
+}
+
+func TestDatabaseCache_Get(t *testing.T) {
+	err := testDatabaseCache.Set("foo", "bar")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// setting the same key again must replace the value
+	err = testDatabaseCache.Set("foo", "baz")
+	if err != nil {
+		t.Error(err)
+	}
+
+	x, err := testDatabaseCache.Get("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if x != "baz" {
+		t.Error("did not found the correct value in cache")
+	}
+
+	_, err = testDatabaseCache.Get("not-there")
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+}
+
+func TestDatabaseCache_Expiry(t *testing.T) {
+	err := testDatabaseCache.Set("expires", "bar", 1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	time.Sleep(2 * time.Second)
+
+	inCache, _ := testDatabaseCache.Has("expires")
+	if inCache {
+		t.Error("expired entry found in cache")
+	}
+
+	err = testDatabaseCache.DeleteExpired()
+	if err != nil {
+		t.Error(err)
+	}
+
+	var count int
+	_ = testDatabaseCache.Conn.QueryRow("SELECT count(*) FROM cache WHERE cache_key = 'expires'").Scan(&count)
+	if count != 0 {
+		t.Error("expired row was not deleted")
+	}
+}
+
+func TestDatabaseCache_Empty(t *testing.T) {
+	err := testDatabaseCache.Set("alpha", "beta")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testDatabaseCache.Empty()
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, err := testDatabaseCache.Has("alpha")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if inCache {
+		t.Error("alpha found in cache, and it should not be there")
+	}
+}
+
+func TestDatabaseCache_EmptyByMatch(t *testing.T) {
+	_ = testDatabaseCache.Set("alpha", "beta")
+	_ = testDatabaseCache.Set("alpha2", "beta2")
+	_ = testDatabaseCache.Set("a_pha", "beta3")
+	_ = testDatabaseCache.Set("beta", "beta")
+	_ = testDatabaseCache.Set("abeta", "beta")
+
+	err := testDatabaseCache.EmptyByMatch("alpha")
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, key := range []string{"alpha", "alpha2"} {
+		inCache, _ := testDatabaseCache.Has(key)
+		if inCache {
+			t.Errorf("%s found in cache, and it should not be there", key)
+		}
+	}
+
+	// _ must be matched literally, not as a LIKE wildcard
+	err = testDatabaseCache.EmptyByMatch("a_")
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, _ := testDatabaseCache.Has("a_pha")
+	if inCache {
+		t.Error("a_pha found in cache, and it should not be there")
+	}
+
+	for _, key := range []string{"beta", "abeta"} {
+		inCache, _ = testDatabaseCache.Has(key)
+		if !inCache {
+			t.Errorf("%s not found in cache, and it should be there", key)
+		}
+	}
+}
+
+func TestDatabaseCache_Query(t *testing.T) {
+	tests := []struct {
+		dataType, table, query, expected string
+	}{
+		{"postgres", "", "SELECT value FROM {table} WHERE cache_key = ? AND expiry > ?", "SELECT value FROM cache WHERE cache_key = $1 AND expiry > $2"},
+		{"pgx", "app_cache", "DELETE FROM {table} WHERE cache_key = ?", "DELETE FROM app_cache WHERE cache_key = $1"},
+		{"mysql", "", "SELECT value FROM {table} WHERE cache_key = ? AND expiry > ?", "SELECT value FROM cache WHERE cache_key = ? AND expiry > ?"},
+		{"sqlite", "", "DELETE FROM {table}", "DELETE FROM cache"},
+	}
+
+	for _, tt := range tests {
+		d := DatabaseCache{DataType: tt.dataType, Table: tt.table}
+		if got := d.query(tt.query); got != tt.expected {
+			t.Errorf("%s: expected %q; got %q", tt.dataType, tt.expected, got)
+		}
+	}
+}
+
+func TestDatabaseCache_Upsert(t *testing.T) {
+	tests := []struct {
+		dataType, expected string
+	}{
+		{"postgres", "INSERT INTO cache (cache_key, value, expiry) VALUES ($1, $2, $3) " +
+			"ON CONFLICT (cache_key) DO UPDATE SET value = excluded.value, expiry = excluded.expiry"},
+		{"mysql", "INSERT INTO cache (cache_key, value, expiry) VALUES (?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE value = VALUES(value), expiry = VALUES(expiry)"},
+		{"mariadb", "INSERT INTO cache (cache_key, value, expiry) VALUES (?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE value = VALUES(value), expiry = VALUES(expiry)"},
+		{"sqlite", "INSERT INTO cache (cache_key, value, expiry) VALUES (?, ?, ?) " +
+			"ON CONFLICT (cache_key) DO UPDATE SET value = excluded.value, expiry = excluded.expiry"},
+	}
+
+	for _, tt := range tests {
+		d := DatabaseCache{DataType: tt.dataType}
+		if got := d.upsert(); got != tt.expected {
+			t.Errorf("%s: expected %q; got %q", tt.dataType, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CountableCache is implemented by caches that can count the keys they hold. Every driver
+// in this package implements it
+type CountableCache interface {
+	Cache
+	Count() (int, error)
+}
+
+var (
+	// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histograms
+	DefaultLatencyBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+	// DefaultPayloadBuckets are the upper bounds, in bytes, of the payload size histogram
+	DefaultPayloadBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+	// KeysCountInterval is how long Stats reuses the number of keys it counted last, as
+	// counting scans every key of redis and badger
+	KeysCountInterval = time.Minute
+)
+
+// InstrumentedCache wraps a Cache and records hits, misses, errors, the latency of every
+// operation and the size of the values read and written, as the wrapped cache encodes them.
+// Measuring a value encodes it a second time.
+//
+// InstrumentedCache implements Rememberer, BatchCache and CountableCache whatever it wraps.
+// Use Cache for a view that also implements TaggableCache and AtomicCache when the wrapped
+// cache does
+type InstrumentedCache struct {
+	cache Cache
+
+	mu         sync.Mutex
+	hits       uint64
+	misses     uint64
+	errors     uint64
+	operations map[string]*OperationStats
+	payloads   Histogram
+	serializer *Serializer
+
+	countMu sync.Mutex
+	keys    int
+	counted time.Time
+}
+
+// Stats is a snapshot of what an InstrumentedCache has recorded since it was created
+type Stats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Errors   uint64  `json:"errors"`
+	HitRatio float64 `json:"hit_ratio"`
+	// Keys is -1 when the cache cannot count its keys
+	Keys         int                       `json:"keys"`
+	Operations   map[string]OperationStats `json:"operations"`
+	PayloadSizes Histogram                 `json:"payload_sizes"`
+}
+
+type OperationStats struct {
+	Calls   uint64    `json:"calls"`
+	Errors  uint64    `json:"errors"`
+	Latency Histogram `json:"latency"`
+}
+
+// Histogram counts observations by size. Counts[i] is the number of observations no larger
+// than Bounds[i] and larger than the bound before it; the last count holds the observations
+// larger than every bound
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+func (h Histogram) copy() Histogram {
+	h.Counts = append([]uint64(nil), h.Counts...)
+	return h
+}
+
+// NewInstrumentedCache returns c wrapped with instrumentation
+func NewInstrumentedCache(c Cache) *InstrumentedCache {
+	return &InstrumentedCache{
+		cache:      c,
+		operations: make(map[string]*OperationStats),
+		payloads:   newHistogram(DefaultPayloadBuckets),
+		serializer: serializerOf(c),
+	}
+}
+
+// Unwrap returns the cache being instrumented
+func (i *InstrumentedCache) Unwrap() Cache {
+	return i.cache
+}
+
+// Cache returns the instrumented cache as a Cache that implements TaggableCache and
+// AtomicCache exactly when the wrapped cache does, with their calls recorded too
+func (i *InstrumentedCache) Cache() Cache {
+	_, taggable := i.cache.(TaggableCache)
+	_, atomic := i.cache.(AtomicCache)
+
+	switch {
+	case taggable && atomic:
+		return instrumentedTaggableAtomic{i}
+	case taggable:
+		return instrumentedTaggable{i}
+	case atomic:
+		return instrumentedAtomic{i}
+	}
+	return i
+}
+
+// record adds one call of op that started at start
+func (i *InstrumentedCache) record(op string, start time.Time, err error) {
+	elapsed := time.Since(start).Seconds()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats, ok := i.operations[op]
+	if !ok {
+		stats = &OperationStats{Latency: newHistogram(DefaultLatencyBuckets)}
+		i.operations[op] = stats
+	}
+
+	stats.Calls++
+	stats.Latency.observe(elapsed)
+	if err != nil {
+		stats.Errors++
+		i.errors++
+	}
+}
+
+// recordPayload records the size of value encoded by the wrapped cache's Serializer, which
+// is what the cache stores and sends
+func (i *InstrumentedCache) recordPayload(value interface{}) {
+	encoded, err := i.serializer.encode(value)
+	if err != nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.payloads.observe(float64(len(encoded)))
+	i.mu.Unlock()
+}
+
+func (i *InstrumentedCache) Has(key string) (bool, error) {
+	start := time.Now()
+	ok, err := i.cache.Has(key)
+	i.record("has", start, err)
+	return ok, err
+}
+
+// Get counts a hit for every value found and a miss for ErrNotFound
+func (i *InstrumentedCache) Get(key string) (interface{}, error) {
+	start := time.Now()
+	value, err := i.cache.Get(key)
+
+	if errors.Is(err, ErrNotFound) {
+		i.record("get", start, nil)
+		i.mu.Lock()
+		i.misses++
+		i.mu.Unlock()
+		return value, err
+	}
+
+	i.record("get", start, err)
+	if err == nil {
+		i.mu.Lock()
+		i.hits++
+		i.mu.Unlock()
+		i.recordPayload(value)
+	}
+
+	return value, err
+}
+
+func (i *InstrumentedCache) Set(key string, value interface{}, expires ...int) error {
+	start := time.Now()
+	err := i.cache.Set(key, value, expires...)
+	i.record("set", start, err)
+	if err == nil {
+		i.recordPayload(value)
+	}
+	return err
+}
+
+func (i *InstrumentedCache) Forget(key string) error {
+	start := time.Now()
+	err := i.cache.Forget(key)
+	i.record("forget", start, err)
+	return err
+}
+
+func (i *InstrumentedCache) EmptyByMatch(prefix string) error {
+	start := time.Now()
+	err := i.cache.EmptyByMatch(prefix)
+	i.record("empty_by_match", start, err)
+	return err
+}
+
+func (i *InstrumentedCache) Empty() error {
+	start := time.Now()
+	err := i.cache.Empty()
+	i.record("empty", start, err)
+	return err
+}
+
+// Count returns the number of keys in the wrapped cache, or -1 if it cannot count them
+func (i *InstrumentedCache) Count() (int, error) {
+	if c, ok := i.cache.(CountableCache); ok {
+		return c.Count()
+	}
+	return -1, nil
+}
+
+// Stats returns what has been recorded so far, with the number of keys counted at most
+// KeysCountInterval ago
+func (i *InstrumentedCache) Stats() Stats {
+	keys := i.countedKeys()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats := Stats{
+		Hits:         i.hits,
+		Misses:       i.misses,
+		Errors:       i.errors,
+		Keys:         keys,
+		Operations:   make(map[string]OperationStats, len(i.operations)),
+		PayloadSizes: i.payloads.copy(),
+	}
+
+	if lookups := i.hits + i.misses; lookups > 0 {
+		stats.HitRatio = float64(i.hits) / float64(lookups)
+	}
+
+	for op, s := range i.operations {
+		stats.Operations[op] = OperationStats{Calls: s.Calls, Errors: s.Errors, Latency: s.Latency.copy()}
+	}
+
+	return stats
+}
+
+// countedKeys returns the number of keys, counting them again once the last count is older
+// than KeysCountInterval
+func (i *InstrumentedCache) countedKeys() int {
+	i.countMu.Lock()
+	defer i.countMu.Unlock()
+
+	if !i.counted.IsZero() && time.Since(i.counted) < KeysCountInterval {
+		return i.keys
+	}
+
+	keys, err := i.Count()
+	if err != nil {
+		// a failed count is not kept, so the next call tries again
+		return -1
+	}
+
+	i.keys, i.counted = keys, time.Now()
+	return keys
+}
+
+// Handler serves Stats as JSON to requests that send token in an "Authorization: Bearer"
+// header. Every request is refused when token is empty
+func (i *InstrumentedCache) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		given := strings.TrimPrefix(auth, "Bearer ")
+		if token == "" || given == auth || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		out, err := json.MarshalIndent(i.Stats(), "", "\t")
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(out)
+	})
+}
+
+// Remember forwards to the wrapped cache when it implements Rememberer, so its distributed
+// locking is kept, and records the call as a whole. Otherwise each get and set is recorded
+func (i *InstrumentedCache) Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	r, ok := i.cache.(Rememberer)
+	if !ok {
+		return remember(i, nil, fmt.Sprintf("%p:%s", i, key), key, ttl, loader, opts...)
+	}
+
+	start := time.Now()
+	value, err := r.Remember(key, ttl, loader, opts...)
+	i.record("remember", start, err)
+	return value, err
+}
+
+// GetMany counts a hit for every key found and a miss for every other key
+func (i *InstrumentedCache) GetMany(keys ...string) (map[string]interface{}, error) {
+	start := time.Now()
+	values, err := GetMany(i.cache, keys...)
+	i.record("get_many", start, err)
+	if err != nil {
+		return values, err
+	}
+
+	i.mu.Lock()
+	i.hits += uint64(len(values))
+	i.misses += uint64(len(keys) - len(values))
+	i.mu.Unlock()
+
+	for _, value := range values {
+		i.recordPayload(value)
+	}
+	return values, nil
+}
+
+func (i *InstrumentedCache) SetMany(items map[string]interface{}, expires ...int) error {
+	start := time.Now()
+	err := SetMany(i.cache, items, expires...)
+	i.record("set_many", start, err)
+	if err == nil {
+		for _, value := range items {
+			i.recordPayload(value)
+		}
+	}
+	return err
+}
+
+func (i *InstrumentedCache) ForgetMany(keys ...string) error {
+	start := time.Now()
+	err := ForgetMany(i.cache, keys...)
+	i.record("forget_many", start, err)
+	return err
+}
+
+// the optional interfaces of the wrapped cache, reached only through the views Cache returns
+
+func (i *InstrumentedCache) setWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	start := time.Now()
+	err := i.cache.(TaggableCache).SetWithTags(key, value, ttl, tags...)
+	i.record("set_with_tags", start, err)
+	if err == nil {
+		i.recordPayload(value)
+	}
+	return err
+}
+
+func (i *InstrumentedCache) flushTags(tags ...string) error {
+	start := time.Now()
+	err := i.cache.(TaggableCache).FlushTags(tags...)
+	i.record("flush_tags", start, err)
+	return err
+}
+
+func (i *InstrumentedCache) increment(key string, by int64) (int64, error) {
+	start := time.Now()
+	n, err := i.cache.(AtomicCache).Increment(key, by)
+	i.record("increment", start, err)
+	return n, err
+}
+
+func (i *InstrumentedCache) decrement(key string, by int64) (int64, error) {
+	start := time.Now()
+	n, err := i.cache.(AtomicCache).Decrement(key, by)
+	i.record("decrement", start, err)
+	return n, err
+}
+
+func (i *InstrumentedCache) add(key string, value interface{}, expires ...int) (bool, error) {
+	start := time.Now()
+	ok, err := i.cache.(AtomicCache).Add(key, value, expires...)
+	i.record("add", start, err)
+	if ok {
+		i.recordPayload(value)
+	}
+	return ok, err
+}
+
+func (i *InstrumentedCache) lock(key string, ttl time.Duration) (*Lock, error) {
+	start := time.Now()
+	l, err := i.cache.(AtomicCache).Lock(key, ttl)
+	// a lock held by another owner is an answer, not a failure of the cache
+	if errors.Is(err, ErrLockNotAcquired) {
+		i.record("lock", start, nil)
+	} else {
+		i.record("lock", start, err)
+	}
+	return l, err
+}
+
+type instrumentedTaggable struct{ *InstrumentedCache }
+
+func (c instrumentedTaggable) SetWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	return c.setWithTags(key, value, ttl, tags...)
+}
+
+func (c instrumentedTaggable) FlushTags(tags ...string) error {
+	return c.flushTags(tags...)
+}
+
+type instrumentedAtomic struct{ *InstrumentedCache }
+
+func (c instrumentedAtomic) Increment(key string, by int64) (int64, error) {
+	return c.increment(key, by)
+}
+
+func (c instrumentedAtomic) Decrement(key string, by int64) (int64, error) {
+	return c.decrement(key, by)
+}
+
+func (c instrumentedAtomic) Add(key string, value interface{}, expires ...int) (bool, error) {
+	return c.add(key, value, expires...)
+}
+
+func (c instrumentedAtomic) Lock(key string, ttl time.Duration) (*Lock, error) {
+	return c.lock(key, ttl)
+}
+
+type instrumentedTaggableAtomic struct{ *InstrumentedCache }
+
+func (c instrumentedTaggableAtomic) SetWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	return c.setWithTags(key, value, ttl, tags...)
+}
+
+func (c instrumentedTaggableAtomic) FlushTags(tags ...string) error {
+	return c.flushTags(tags...)
+}
+
+func (c instrumentedTaggableAtomic) Increment(key string, by int64) (int64, error) {
+	return c.increment(key, by)
+}
+
+func (c instrumentedTaggableAtomic) Decrement(key string, by int64) (int64, error) {
+	return c.decrement(key, by)
+}
+
+func (c instrumentedTaggableAtomic) Add(key string, value interface{}, expires ...int) (bool, error) {
+	return c.add(key, value, expires...)
+}
+
+func (c instrumentedTaggableAtomic) Lock(key string, ttl time.Duration) (*Lock, error) {
+	return c.lock(key, ttl)
+}
@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstrumentedCache_Stats(t *testing.T) {
+	ic := NewInstrumentedCache(NewMemoryCache(0, 0))
+
+	_ = ic.Set("foo", "bar")
+	_ = ic.Set("big", strings.Repeat("x", 2000))
+	_, _ = ic.Get("foo")
+	_, _ = ic.Get("foo")
+	_, _ = ic.Get("missing")
+	_ = ic.Forget("big")
+
+	stats := ic.Stats()
+
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss; got %d and %d", stats.Hits, stats.Misses)
+	}
+
+	if stats.HitRatio < 0.66 || stats.HitRatio > 0.67 {
+		t.Errorf("expected a hit ratio of 2/3; got %f", stats.HitRatio)
+	}
+
+	if stats.Keys != 1 {
+		t.Errorf("expected 1 key; got %d", stats.Keys)
+	}
+
+	get := stats.Operations["get"]
+	if get.Calls != 3 || get.Latency.Count != 3 {
+		t.Errorf("expected 3 timed get calls; got %+v", get)
+	}
+
+	if stats.Operations["forget"].Calls != 1 {
+		t.Error("forget was not recorded")
+	}
+
+	// two sets and two hits were measured as gob encodes them, one of them larger than 1024 bytes
+	sizes := stats.PayloadSizes
+	if sizes.Count != 4 || sizes.Counts[0] != 3 || sizes.Counts[3] != 1 {
+		t.Errorf("unexpected payload sizes %+v", sizes)
+	}
+}
+
+func TestInstrumentedCache_PayloadSizes(t *testing.T) {
+	c := testRedisCache
+	c.Serializer = &Serializer{Codec: JSONCodec}
+	ic := NewInstrumentedCache(&c)
+
+	// values that are not strings are measured too, as the wrapped cache encodes them
+	value := map[string]interface{}{"name": strings.Repeat("x", 100)}
+	if err := ic.Set("payload", value); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := c.Serializer.encode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := ic.Stats().PayloadSizes
+	if sizes.Count != 1 || sizes.Sum != float64(len(encoded)) {
+		t.Errorf("expected one payload of %d bytes; got %+v", len(encoded), sizes)
+	}
+}
+
+func TestInstrumentedCache_KeysCountInterval(t *testing.T) {
+	var scans int
+	ic := NewInstrumentedCache(pagingRedisCache(&scans))
+
+	_ = testRedisCache.Empty()
+	_ = testRedisCache.Set("counted", 1)
+
+	if keys := ic.Stats().Keys; keys != 1 {
+		t.Fatalf("expected 1 key; got %d", keys)
+	}
+
+	// the count is reused until KeysCountInterval has passed
+	_ = testRedisCache.Set("not-counted", 1)
+	counted := scans
+	if keys := ic.Stats().Keys; keys != 1 || scans != counted {
+		t.Errorf("expected the last count without a scan; got %d keys after %d scans", keys, scans-counted)
+	}
+
+	ic.countMu.Lock()
+	ic.counted = time.Now().Add(-KeysCountInterval)
+	ic.countMu.Unlock()
+
+	if keys := ic.Stats().Keys; keys != 2 {
+		t.Errorf("expected the keys to be counted again; got %d", keys)
+	}
+}
+
+func TestInstrumentedCache_Errors(t *testing.T) {
+	ic := NewInstrumentedCache(NewMemoryCache(0, 10))
+
+	err := ic.Set("too-big", strings.Repeat("x", 100))
+	if err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge; got %v", err)
+	}
+
+	stats := ic.Stats()
+	if stats.Errors != 1 || stats.Operations["set"].Errors != 1 {
+		t.Errorf("expected 1 error; got %+v", stats)
+	}
+}
+
+func TestInstrumentedCache_Handler(t *testing.T) {
+	ic := NewInstrumentedCache(NewMemoryCache(0, 0))
+	_, _ = ic.Get("missing")
+
+	for _, header := range []string{"", "Bearer wrong", "metrics-token", "Basic metrics-token"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", header)
+		ic.Handler("metrics-token").ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected %q to be refused; got %d", header, rr.Code)
+		}
+	}
+
+	// an empty token refuses everyone
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	ic.Handler("").ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected an empty token to refuse every request; got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer metrics-token")
+	ic.Handler("metrics-token").ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected content type %s", rr.Header().Get("Content-Type"))
+	}
+
+	var stats Stats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Misses != 1 {
+		t.Errorf("expected 1 miss; got %d", stats.Misses)
+	}
+}
+
+func TestCountableCache_Count(t *testing.T) {
+	for name, c := range map[string]CountableCache{"redis": &testRedisCache, "badger": testBadgerCache, "sql": &testDatabaseCache} {
+		_ = c.Empty()
+		_ = c.Set("count:1", "one")
+		_ = c.Set("count:2", "two")
+
+		n, err := c.Count()
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if n != 2 {
+			t.Errorf("%s: expected 2 keys; got %d", name, n)
+		}
+	}
+}
+
+func TestRedisCache_CountScanPages(t *testing.T) {
+	var scans int
+	c := pagingRedisCache(&scans)
+
+	_ = testRedisCache.Empty()
+	for i := 0; i < 11; i++ {
+		_ = testRedisCache.Set(fmt.Sprintf("count:%d", i), i)
+	}
+
+	n, err := c.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 11 || scans < 2 {
+		t.Errorf("expected 11 keys over several pages; got %d keys over %d pages", n, scans)
+	}
+
+	stats := NewInstrumentedCache(c).Stats()
+	if stats.Keys != 11 {
+		t.Errorf("expected stats to count 11 keys; got %d", stats.Keys)
+	}
+}
+
+func TestInstrumentedCache_OptionalInterfaces(t *testing.T) {
+	tests := []struct {
+		name             string
+		cache            Cache
+		taggable, atomic bool
+	}{
+		{"memory", NewMemoryCache(0, 0), false, false},
+		{"redis", &testRedisCache, true, true},
+		{"badger", testBadgerCache, true, true},
+	}
+
+	for _, tt := range tests {
+		ic := NewInstrumentedCache(tt.cache)
+		c := ic.Cache()
+
+		if _, ok := c.(TaggableCache); ok != tt.taggable {
+			t.Errorf("%s: expected TaggableCache to be %v", tt.name, tt.taggable)
+		}
+		if _, ok := c.(AtomicCache); ok != tt.atomic {
+			t.Errorf("%s: expected AtomicCache to be %v", tt.name, tt.atomic)
+		}
+		if _, ok := c.(BatchCache); !ok {
+			t.Errorf("%s: expected BatchCache", tt.name)
+		}
+		if _, ok := c.(Rememberer); !ok {
+			t.Errorf("%s: expected Rememberer", tt.name)
+		}
+
+		_ = c.Empty()
+
+		if tc, ok := c.(TaggableCache); ok {
+			if err := tc.SetWithTags("tagged", "value", 60, "group"); err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			}
+			if err := tc.FlushTags("group"); err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			}
+			if ok, _ := c.Has("tagged"); ok {
+				t.Errorf("%s: expected the tagged key to be flushed", tt.name)
+			}
+		}
+
+		if ac, ok := c.(AtomicCache); ok {
+			if n, err := ac.Increment("hits", 2); err != nil || n != 2 {
+				t.Errorf("%s: expected 2; got %d, %v", tt.name, n, err)
+			}
+			l, err := ac.Lock("job", time.Second)
+			if err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			} else {
+				_ = l.Release()
+			}
+		}
+
+		_ = SetMany(c, map[string]interface{}{"a": "1", "b": "2"})
+		values, err := GetMany(c, "a", "b", "missing")
+		if err != nil || len(values) != 2 {
+			t.Errorf("%s: expected 2 values; got %v, %v", tt.name, values, err)
+		}
+
+		value, err := Remember(c, "remembered", 60, func() (interface{}, error) { return "loaded", nil })
+		if err != nil || value != "loaded" {
+			t.Errorf("%s: expected loaded; got %v, %v", tt.name, value, err)
+		}
+
+		stats := ic.Stats()
+		if stats.Operations["get_many"].Calls != 1 || stats.Misses < 1 {
+			t.Errorf("%s: expected get_many and its miss to be recorded; got %+v", tt.name, stats)
+		}
+		if tt.atomic && stats.Operations["increment"].Calls != 1 {
+			t.Errorf("%s: expected increment to be recorded", tt.name)
+		}
+		if tt.taggable && stats.Operations["flush_tags"].Calls != 1 {
+			t.Errorf("%s: expected flush_tags to be recorded", tt.name)
+		}
+	}
+}
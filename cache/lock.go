@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+// locker is implemented by drivers that can hold a lock shared by every process using the
+// same store. The token identifies the holder, so a lock that expired and was taken by
+// another process is never released or renewed by the first
+type locker interface {
+	lock(key string, ttl time.Duration) (token string, ok bool, err error)
+	unlock(key, token string) error
+	renew(key, token string, ttl time.Duration) (bool, error)
+}
+
+var (
+	// ErrLockNotAcquired is returned by Lock when another holder has the lock
+	ErrLockNotAcquired = errors.New("cache: lock is held by another owner")
+	// ErrLockLost is returned by Renew when the lock expired and may have been taken by
+	// another owner
+	ErrLockLost = errors.New("cache: lock is no longer held")
+
+	errLocked = errors.New("cache: key is locked")
+)
+
+// Lock is a lock taken with Lock. It expires after its ttl unless it is renewed, so a
+// process that dies while holding it does not block the others forever
+type Lock struct {
+	key    string
+	token  string
+	locker locker
+}
+
+func acquire(l locker, key string, ttl time.Duration) (*Lock, error) {
+	token, ok, err := l.lock(key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	return &Lock{key: key, token: token, locker: l}, nil
+}
+
+// Token returns the random token that identifies this owner of the lock
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Release gives up the lock. Releasing a lock that expired and was taken by another owner
+// leaves the new owner's lock in place
+func (l *Lock) Release() error {
+	return l.locker.unlock(l.key, l.token)
+}
+
+// Renew extends the lock to ttl from now. It returns ErrLockLost if the lock has expired
+func (l *Lock) Renew(ttl time.Duration) error {
+	ok, err := l.locker.renew(l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// deletes KEYS[1] only while it still holds the caller's token
+var redisUnlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (c *RedisCache) lock(key string, ttl time.Duration) (string, bool, error) {
+	token, err := lockToken()
+	if err != nil {
+		return "", false, err
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	_, err = redis.String(conn.Do("SET", c.key(key), token, "NX", "PX", ttl.Milliseconds()))
+	if err == redis.ErrNil {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return token, true, nil
+}
+
+func (c *RedisCache) unlock(key, token string) error {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	_, err := redisUnlockScript.Do(conn, c.key(key), token)
+	return err
+}
+
+// extends KEYS[1] to ARGV[2] milliseconds only while it still holds the caller's token
+var redisRenewScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+func (c *RedisCache) renew(key, token string, ttl time.Duration) (bool, error) {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	return redis.Bool(redisRenewScript.Do(conn, c.key(key), token, ttl.Milliseconds()))
+}
+
+func (b BadgerCache) lock(key string, ttl time.Duration) (string, bool, error) {
+	token, err := lockToken()
+	if err != nil {
+		return "", false, err
+	}
+
+	err = b.Conn.Update(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		if err == nil {
+			return errLocked
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		return txn.SetEntry(badger.NewEntry([]byte(key), []byte(token)).WithTTL(ttl))
+	})
+
+	// a conflict means another transaction took the lock first
+	if err == errLocked || err == badger.ErrConflict {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return token, true, nil
+}
+
+func (b BadgerCache) unlock(key, token string) error {
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		held, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		if string(held) != token {
+			return nil
+		}
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (b BadgerCache) renew(key, token string, ttl time.Duration) (bool, error) {
+	err := b.Conn.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return ErrLockLost
+		} else if err != nil {
+			return err
+		}
+
+		held, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		if string(held) != token {
+			return ErrLockLost
+		}
+		return txn.SetEntry(badger.NewEntry([]byte(key), held).WithTTL(ttl))
+	})
+
+	if err == ErrLockLost {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooLarge is returned when a single value is bigger than the cache's MaxBytes
+var ErrTooLarge = errors.New("cache: value is larger than the cache size limit")
+
+// MemoryCache is a thread-safe in-process cache. Values are stored encoded, exactly as the
+// other drivers store them, and the least recently used entries are evicted once the cache
+// holds more than MaxEntries entries or MaxBytes bytes. A limit of zero means no limit
+type MemoryCache struct {
+	MaxEntries int
+	MaxBytes   int64
+	// Serializer encodes the values; nil encodes with gob
+	Serializer *Serializer
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List
+	size       int64
+}
+
+type memoryItem struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && now.After(i.expires)
+}
+
+func (i *memoryItem) size() int64 {
+	return int64(len(i.key) + len(i.value))
+}
+
+// NewMemoryCache returns an empty MemoryCache with the given limits
+func NewMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (m *MemoryCache) Has(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.get(key)
+	return ok, nil
+}
+
+func (m *MemoryCache) Get(key string) (interface{}, error) {
+	m.mu.Lock()
+	item, ok := m.get(key)
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return m.Serializer.decode(item.value)
+}
+
+func (m *MemoryCache) Set(key string, value interface{}, expires ...int) error {
+	encoded, err := m.Serializer.encode(value)
+	if err != nil {
+		return err
+	}
+
+	item := &memoryItem{key: key, value: encoded}
+	if len(expires) > 0 && expires[0] > 0 {
+		item.expires = time.Now().Add(time.Second * time.Duration(expires[0]))
+	}
+
+	if m.MaxBytes > 0 && item.size() > m.MaxBytes {
+		return ErrTooLarge
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.init()
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+
+	m.items[key] = m.lru.PushFront(item)
+	m.size += item.size()
+	m.evict()
+
+	return nil
+}
+
+func (m *MemoryCache) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+// EmptyByMatch removes every entry whose key starts with prefix
+func (m *MemoryCache) EmptyByMatch(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCache) Empty() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = make(map[string]*list.Element)
+	m.lru = list.New()
+	m.size = 0
+	return nil
+}
+
+// DeleteExpired removes every expired entry. Expired entries are never returned, but they
+// take up space until they are looked up, evicted or removed here
+func (m *MemoryCache) DeleteExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, el := range m.items {
+		if el.Value.(*memoryItem).expired(now) {
+			m.remove(el)
+		}
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries not yet removed
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.items)
+}
+
+// get returns the live item for key and marks it as recently used. m.mu must be held
+func (m *MemoryCache) get(key string) (*memoryItem, bool) {
+	m.init()
+
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := el.Value.(*memoryItem)
+	if item.expired(time.Now()) {
+		m.remove(el)
+		return nil, false
+	}
+
+	m.lru.MoveToFront(el)
+	return item, true
+}
+
+// evict drops the least recently used entries until the cache is within its limits. m.mu must be held
+func (m *MemoryCache) evict() {
+	for (m.MaxEntries > 0 && len(m.items) > m.MaxEntries) || (m.MaxBytes > 0 && m.size > m.MaxBytes) {
+		el := m.lru.Back()
+		if el == nil {
+			return
+		}
+		m.remove(el)
+	}
+}
+
+// remove deletes el from the cache. m.mu must be held
+func (m *MemoryCache) remove(el *list.Element) {
+	item := m.lru.Remove(el).(*memoryItem)
+	delete(m.items, item.key)
+	m.size -= item.size()
+}
+
+// init allows a zero MemoryCache to be used. m.mu must be held
+func (m *MemoryCache) init() {
+	if m.items == nil {
+		m.items = make(map[string]*list.Element)
+		m.lru = list.New()
+	}
+}
+
+// Remember returns the value at key, or loads it with loader and stores it for ttl seconds.
+// Concurrent calls for the same key share one loader call
+func (m *MemoryCache) Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	return remember(m, nil, fmt.Sprintf("%p:%s", m, key), key, ttl, loader, opts...)
+}
+
+// Count returns the number of entries, as Len does, to implement CountableCache
+func (m *MemoryCache) Count() (int, error) {
+	return m.Len(), nil
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_Has(t *testing.T) {
+	err := testMemoryCache.Forget("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, err := testMemoryCache.Has("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if inCache {
+		t.Error("foo found in cache and it shouldn`t be there!")
+	}
+
+	_ = testMemoryCache.Set("foo", "bar")
+
+	inCache, err = testMemoryCache.Has("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !inCache {
+		t.Error("foo not found in cache")
+	}
+}
+
+func TestMemoryCache_Get(t *testing.T) {
+	err := testMemoryCache.Set("foo", "bar")
+	if err != nil {
+		t.Error(err)
+	}
+
+	x, err := testMemoryCache.Get("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if x != "bar" {
+		t.Error("did not found the correct value in cache")
+	}
+
+	_, err = testMemoryCache.Get("not-there")
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+}
+
+func TestMemoryCache_Expiry(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+
+	err := mc.Set("foo", "bar", 1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	mc.items["foo"].Value.(*memoryItem).expires = time.Now().Add(-time.Second)
+
+	inCache, _ := mc.Has("foo")
+	if inCache {
+		t.Error("foo has expired and should not be in the cache")
+	}
+}
+
+func TestMemoryCache_Eviction(t *testing.T) {
+	mc := NewMemoryCache(2, 0)
+
+	_ = mc.Set("a", 1)
+	_ = mc.Set("b", 2)
+	_, _ = mc.Get("a")
+	_ = mc.Set("c", 3)
+
+	if inCache, _ := mc.Has("b"); inCache {
+		t.Error("b is the least recently used entry and should have been evicted")
+	}
+
+	if inCache, _ := mc.Has("a"); !inCache {
+		t.Error("a should still be in the cache")
+	}
+
+	_ = mc.Set("big", "value")
+	limited := NewMemoryCache(0, mc.size)
+	_ = limited.Set("x", 1)
+	_ = limited.Set("y", 2)
+	if limited.size > limited.MaxBytes {
+		t.Errorf("cache holds %d bytes, more than its limit of %d", limited.size, limited.MaxBytes)
+	}
+
+	err := NewMemoryCache(0, 1).Set("foo", "bar")
+	if err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge; got %v", err)
+	}
+}
+
+func TestMemoryCache_Empty(t *testing.T) {
+	err := testMemoryCache.Set("alpha", "beta")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testMemoryCache.Empty()
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, err := testMemoryCache.Has("alpha")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if inCache {
+		t.Error("alpha should have been deleted from cache")
+	}
+}
+
+func TestMemoryCache_EmptyByMatch(t *testing.T) {
+	_ = testMemoryCache.Set("alpha", "beta")
+	_ = testMemoryCache.Set("alpha2", "beta2")
+	_ = testMemoryCache.Set("bar", "bar")
+
+	err := testMemoryCache.EmptyByMatch("a")
+	if err != nil {
+		t.Error(err)
+	}
+
+	inCache, _ := testMemoryCache.Has("alpha")
+	if inCache {
+		t.Error("alpha should have been deleted from cache")
+	}
+
+	inCache, _ = testMemoryCache.Has("alpha2")
+	if inCache {
+		t.Error("alpha2 should have been deleted from cache")
+	}
+
+	inCache, _ = testMemoryCache.Has("bar")
+	if !inCache {
+		t.Error("bar should still be in the cache")
+	}
+}
@@ -6,8 +6,9 @@ import (
 )
 
 type RedisCache struct {
-	Conn   *redis.Pool
-	Prefix string
+	Conn       *redis.Pool
+	Prefix     string
+	Serializer *Serializer
 }
 
 func (c *RedisCache) key(str string) string {
@@ -20,18 +21,18 @@ func (c *RedisCache) Get(key string) (interface{}, error) {
 	defer conn.Close()
 
 	cacheEntry, err := redis.Bytes(conn.Do("GET", key))
-	if err != nil {
+	if err == redis.ErrNil {
+		return nil, ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
-	decoded, err := decode(string(cacheEntry))
-	if err != nil {
-		return nil, err
+	// counters are written by INCRBY as plain numbers
+	if n, ok := parseCounter(cacheEntry); ok {
+		return n, nil
 	}
 
-	item := decoded[key]
-
-	return item, nil
+	return c.Serializer.decode(cacheEntry)
 }
 
 func (c *RedisCache) Set(key string, value interface{}, expires ...int) error {
@@ -39,13 +40,9 @@ func (c *RedisCache) Set(key string, value interface{}, expires ...int) error {
 	conn := c.Conn.Get()
 	defer conn.Close()
 
-	var err error
-
-	entry := Entry{}
-	entry[key] = value
-	encoded, err := encode(entry)
+	encoded, err := c.Serializer.encode(value)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	if len(expires) > 0 {
@@ -74,22 +71,12 @@ func (c *RedisCache) EmptyByMatch(key string) error {
 
 func (c *RedisCache) forgetByKeys(pattern string) error {
 
-	conn := c.Conn.Get()
-	defer conn.Close()
-
 	keys, err := c.getKeys(pattern)
 	if err != nil {
 		return err
 	}
 
-	for _, x := range keys {
-		_, err := conn.Do("DEL", x)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return c.deleteKeys(keys)
 }
 
 func (c *RedisCache) Empty() error {
@@ -124,7 +111,7 @@ func (c *RedisCache) getKeys(pattern string) ([]string, error) {
 			return keys, err
 		}
 
-		iter, _ := redis.Int(arr[0], nil)
+		iter, _ = redis.Int(arr[0], nil)
 		k, _ := redis.Strings(arr[1], nil)
 		keys = append(keys, k...)
 
@@ -135,3 +122,17 @@ func (c *RedisCache) getKeys(pattern string) ([]string, error) {
 
 	return keys, nil
 }
+
+// Remember returns the value at key, or loads it with loader and stores it for ttl seconds.
+// Concurrent calls for the same key in this process share one loader call; WithLock extends
+// that to every process using the same redis
+func (c *RedisCache) Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	return remember(c, c, fmt.Sprintf("%p:%s", c.Conn, c.key(key)), key, ttl, loader, opts...)
+}
+
+// Count returns the number of keys under the cache's prefix. It scans every key, so it is
+// meant for tools and statistics rather than requests
+func (c *RedisCache) Count() (int, error) {
+	keys, err := c.getKeys(c.key("*"))
+	return len(keys), err
+}
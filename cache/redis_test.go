@@ -1,6 +1,14 @@
 package cache
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
 
 func TestRedisCache_Has(t *testing.T) {
 
@@ -140,3 +148,97 @@ func TestRedisCache_EmptyByMatch(t *testing.T) {
 		t.Error("beta should still be in the cache")
 	}
 }
+
+// pagingConn serves SCAN two keys at a time, as redis does for a large keyspace, where
+// miniredis returns every key at once and ignores COUNT
+type pagingConn struct {
+	redis.Conn
+	scans *int
+}
+
+func (c pagingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "SCAN" {
+		return c.Conn.Do(cmd, args...)
+	}
+
+	*c.scans++
+	if *c.scans > 1000 {
+		return nil, errors.New("SCAN called 1000 times; the cursor is not advancing")
+	}
+
+	cursor, _ := strconv.Atoi(fmt.Sprint(args[0]))
+	all, err := redis.Values(c.Conn.Do("SCAN", append([]interface{}{0}, args[1:]...)...))
+	if err != nil {
+		return nil, err
+	}
+	keys, _ := redis.Strings(all[1], nil)
+	sort.Strings(keys)
+
+	end, next := cursor+2, cursor+2
+	if end >= len(keys) {
+		end, next = len(keys), 0
+	}
+
+	page := make([]interface{}, 0, 2)
+	for _, k := range keys[cursor:end] {
+		page = append(page, []byte(k))
+	}
+	return []interface{}{[]byte(strconv.Itoa(next)), page}, nil
+}
+
+// pagingRedisCache shares the test redis, scanning it a page at a time
+func pagingRedisCache(scans *int) *RedisCache {
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			conn, err := testRedisCache.Conn.Dial()
+			if err != nil {
+				return nil, err
+			}
+			return pagingConn{Conn: conn, scans: scans}, nil
+		},
+	}
+
+	return &RedisCache{Conn: pool, Prefix: testRedisCache.Prefix, Serializer: testRedisCache.Serializer}
+}
+
+func TestRedisCache_ScanPages(t *testing.T) {
+	var scans int
+	c := pagingRedisCache(&scans)
+
+	fill := func() {
+		_ = testRedisCache.Empty()
+		for i := 0; i < 11; i++ {
+			_ = testRedisCache.Set(fmt.Sprintf("page:%d", i), i)
+		}
+		_ = testRedisCache.Set("other", "kept")
+		scans = 0
+	}
+
+	fill()
+	keys, err := c.getKeys(c.key("page:*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 11 || scans != 6 {
+		t.Errorf("expected 11 keys over 6 pages; got %d keys over %d pages", len(keys), scans)
+	}
+
+	fill()
+	if err := c.EmptyByMatch("page:"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := testRedisCache.Has("page:10"); ok {
+		t.Error("expected every page: key to be deleted")
+	}
+	if ok, _ := testRedisCache.Has("other"); !ok {
+		t.Error("expected other to be kept")
+	}
+
+	fill()
+	if err := c.Empty(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := testRedisCache.Has("other"); ok {
+		t.Error("expected the cache to be empty")
+	}
+}
@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rememberer is implemented by caches that can load and store a missing value in one call.
+// Every driver in this package implements it
+type Rememberer interface {
+	Remember(key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error)
+}
+
+// Remember calls c.Remember when c implements Rememberer, and otherwise loads missing values
+// with only in-process deduplication
+func Remember(c Cache, key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	if r, ok := c.(Rememberer); ok {
+		return r.Remember(key, ttl, loader, opts...)
+	}
+	return remember(c, nil, fmt.Sprintf("%p:%s", c, key), key, ttl, loader, opts...)
+}
+
+// RememberOption changes how a single Remember call loads and refreshes its value
+type RememberOption func(*rememberOptions)
+
+type rememberOptions struct {
+	lock  time.Duration
+	beta  float64
+	stale time.Duration
+}
+
+// WithLock takes a lock in the cache before calling the loader, so only one process
+// recomputes a missing key; the others wait for its value. ttl is how long the lock is held
+// at most, should the process holding it die. Only RedisCache and BadgerCache can lock;
+// other drivers ignore this option
+func WithLock(ttl time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.lock = ttl
+	}
+}
+
+// WithEarlyRefresh refreshes a value in the background before it expires, with a chance
+// that rises as expiry nears and with the time the loader took. beta 1 is a good start;
+// higher values refresh earlier
+func WithEarlyRefresh(beta float64) RememberOption {
+	return func(o *rememberOptions) {
+		o.beta = beta
+	}
+}
+
+// WithStale keeps an expired value for up to d longer, and returns it while a fresh value
+// is loaded in the background
+func WithStale(d time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.stale = d
+	}
+}
+
+// tracksExpiry reports whether the logical expiry of the value must be stored next to it
+func (o rememberOptions) tracksExpiry() bool {
+	return o.beta > 0 || o.stale > 0
+}
+
+// rememberMeta is stored at key + metaSuffix when a value is refreshed early or served stale
+type rememberMeta struct {
+	expires time.Time
+	delta   time.Duration
+}
+
+const (
+	metaSuffix = ":remember"
+	lockSuffix = ":lock"
+)
+
+func (m rememberMeta) String() string {
+	return strconv.FormatInt(m.expires.UnixNano(), 10) + " " + strconv.FormatInt(int64(m.delta), 10)
+}
+
+func parseRememberMeta(v interface{}) (rememberMeta, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return rememberMeta{}, false
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return rememberMeta{}, false
+	}
+
+	expires, err1 := strconv.ParseInt(fields[0], 10, 64)
+	delta, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return rememberMeta{}, false
+	}
+
+	return rememberMeta{expires: time.Unix(0, expires), delta: time.Duration(delta)}, true
+}
+
+// refreshEarly decides whether to refresh a value that has not expired yet, using the
+// XFetch algorithm: the longer the loader takes and the closer expiry is, the likelier
+func (m rememberMeta) refreshEarly(beta float64, now time.Time) bool {
+	if beta <= 0 {
+		return false
+	}
+	gap := float64(m.delta) * beta * -math.Log(1-rand.Float64())
+	return !now.Add(time.Duration(gap)).Before(m.expires)
+}
+
+// remember is the shared implementation of the drivers' Remember methods. flightKey must be
+// unique to the cache instance and key, so concurrent loads of the same key in this process
+// share a single loader call. l is nil for drivers that cannot lock
+func remember(c Cache, l locker, flightKey, key string, ttl int, loader func() (interface{}, error), opts ...RememberOption) (interface{}, error) {
+	o := rememberOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	load := func() (interface{}, error) {
+		return flights.do(flightKey, func() (interface{}, error) {
+			return rememberLoad(c, l, key, ttl, loader, o)
+		})
+	}
+
+	value, err := c.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return load()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !o.tracksExpiry() {
+		return value, nil
+	}
+
+	// a value stored with Set has no meta, and is used until it expires
+	raw, err := c.Get(key + metaSuffix)
+	if err != nil {
+		return value, nil
+	}
+	meta, ok := parseRememberMeta(raw)
+	if !ok {
+		return value, nil
+	}
+
+	now := time.Now()
+	if now.Before(meta.expires) && !meta.refreshEarly(o.beta, now) {
+		return value, nil
+	}
+
+	go func() {
+		_, _ = load()
+	}()
+
+	return value, nil
+}
+
+func rememberLoad(c Cache, l locker, key string, ttl int, loader func() (interface{}, error), o rememberOptions) (interface{}, error) {
+	if l != nil && o.lock > 0 {
+		token, ok, err := l.lock(key+lockSuffix, o.lock)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			defer func() {
+				_ = l.unlock(key+lockSuffix, token)
+			}()
+		} else if value, err := waitFor(c, key, o.lock); err == nil {
+			return value, nil
+		}
+		// the lock holder did not store a value in time; load it here instead
+	}
+
+	start := time.Now()
+	value, err := loader()
+	if err != nil {
+		return nil, err
+	}
+	delta := time.Since(start)
+
+	if ttl <= 0 {
+		return value, c.Set(key, value)
+	}
+
+	expires := ttl
+	if o.stale > 0 {
+		expires += int(math.Ceil(o.stale.Seconds()))
+	}
+
+	if err := c.Set(key, value, expires); err != nil {
+		return value, err
+	}
+
+	if o.tracksExpiry() {
+		meta := rememberMeta{expires: start.Add(delta + time.Duration(ttl)*time.Second), delta: delta}
+		return value, c.Set(key+metaSuffix, meta.String(), expires)
+	}
+
+	return value, nil
+}
+
+// waitFor polls the cache until key has a value or max has passed
+func waitFor(c Cache, key string, max time.Duration) (interface{}, error) {
+	deadline := time.Now().Add(max)
+	for {
+		value, err := c.Get(key)
+		if !errors.Is(err, ErrNotFound) {
+			return value, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrNotFound
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// flights deduplicates concurrent loads of the same key within this process
+var flights = &flightGroup{}
+
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// do calls fn once for every group of concurrent callers with the same key, and gives each
+// of them its result
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		call.wg.Done()
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+
+	call.value, call.err = fn()
+	return call.value, call.err
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestRemember_SingleFlight(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+
+	var calls int32
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "loaded", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := mc.Remember("hot", 60, loader)
+			if err != nil {
+				t.Error(err)
+			}
+			if v != "loaded" {
+				t.Errorf("expected loaded; got %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected the loader to be called once; called %d times", calls)
+	}
+}
+
+func TestRemember_Lock(t *testing.T) {
+	_ = testRedisCache.Forget("locked-hot")
+
+	// two caches with their own pools stand in for two processes
+	first := RedisCache{Conn: &redis.Pool{Dial: testRedisCache.Conn.Dial}, Prefix: testRedisCache.Prefix}
+	second := RedisCache{Conn: &redis.Pool{Dial: testRedisCache.Conn.Dial}, Prefix: testRedisCache.Prefix}
+	defer first.Conn.Close()
+	defer second.Conn.Close()
+
+	var calls int32
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "loaded", nil
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range []*RedisCache{&first, &second} {
+		wg.Add(1)
+		go func(c *RedisCache) {
+			defer wg.Done()
+			v, err := c.Remember("locked-hot", 60, loader, WithLock(5*time.Second))
+			if err != nil {
+				t.Error(err)
+			}
+			if v != "loaded" {
+				t.Errorf("expected loaded; got %v", v)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected the loader to be called once; called %d times", calls)
+	}
+}
+
+func TestLocker(t *testing.T) {
+	for name, l := range map[string]locker{"redis": &testRedisCache, "badger": testBadgerCache} {
+		token, ok, err := l.lock("lock-test", time.Minute)
+		if err != nil || !ok {
+			t.Fatalf("%s: could not take a free lock: %v", name, err)
+		}
+
+		_, ok, _ = l.lock("lock-test", time.Minute)
+		if ok {
+			t.Errorf("%s: took a lock that is already held", name)
+		}
+
+		_ = l.unlock("lock-test", "not-the-owner")
+		_, ok, _ = l.lock("lock-test", time.Minute)
+		if ok {
+			t.Errorf("%s: lock was released by a caller that does not hold it", name)
+		}
+
+		_ = l.unlock("lock-test", token)
+		token, ok, _ = l.lock("lock-test", time.Minute)
+		if !ok {
+			t.Errorf("%s: lock was not released by its owner", name)
+		}
+		_ = l.unlock("lock-test", token)
+	}
+}
+
+func TestRemember_Stale(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+
+	v, _ := mc.Remember("stale", 1, func() (interface{}, error) {
+		return "old", nil
+	}, WithStale(time.Minute))
+	if v != "old" {
+		t.Errorf("expected old; got %v", v)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	refreshed := make(chan struct{})
+	v, _ = mc.Remember("stale", 1, func() (interface{}, error) {
+		defer close(refreshed)
+		return "new", nil
+	}, WithStale(time.Minute))
+	if v != "old" {
+		t.Errorf("expected the stale value; got %v", v)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("value was not refreshed in the background")
+	}
+
+	// give the refresh time to store its value
+	time.Sleep(20 * time.Millisecond)
+	v, _ = mc.Get("stale")
+	if v != "new" {
+		t.Errorf("expected new; got %v", v)
+	}
+}
+
+func TestRememberMeta_RefreshEarly(t *testing.T) {
+	now := time.Now()
+
+	near := rememberMeta{expires: now.Add(time.Millisecond), delta: time.Hour}
+	if !near.refreshEarly(1, now) {
+		t.Error("expected a slow value about to expire to be refreshed")
+	}
+
+	far := rememberMeta{expires: now.Add(time.Hour), delta: time.Millisecond}
+	if far.refreshEarly(1, now) {
+		t.Error("expected a fast value far from expiry not to be refreshed")
+	}
+
+	meta, ok := parseRememberMeta(near.String())
+	if !ok || !meta.expires.Equal(near.expires) || meta.delta != near.delta {
+		t.Errorf("meta did not survive a round trip: %v", meta)
+	}
+}
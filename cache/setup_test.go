@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"database/sql"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/gomodule/redigo/redis"
@@ -8,10 +9,14 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	_ "modernc.org/sqlite"
 )
 
 var testRedisCache RedisCache
 var testBadgerCache BadgerCache
+var testMemoryCache = NewMemoryCache(100, 0)
+var testDatabaseCache DatabaseCache
 
 func TestMain(m *testing.M) {
 	s, err := miniredis.Run()
@@ -38,7 +43,7 @@ func TestMain(m *testing.M) {
 
 	// create a badger database
 	if _, err := os.Stat("./testdata/tmp"); os.IsNotExist(err) {
-		err := os.Mkdir("./testdata/tmp", 0755)
+		err := os.MkdirAll("./testdata/tmp", 0755)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -52,8 +57,24 @@ func TestMain(m *testing.M) {
 	db, _ := badger.Open(badger.DefaultOptions("./testdata/tmp/badger"))
 	testBadgerCache.Conn = db
 
+	// create a sqlite database with the same cache table as ugo make cache-table
+	_ = os.Remove("./testdata/tmp/cache.db")
+	sqlDB, err := sql.Open("sqlite", "./testdata/tmp/cache.db")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err = sqlDB.Exec(`CREATE TABLE cache (cache_key TEXT PRIMARY KEY, value BLOB NOT NULL, expiry INTEGER NULL)`)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	testDatabaseCache.Conn = sqlDB
+	testDatabaseCache.DataType = "sqlite"
+
 	code := m.Run()
 	_ = os.RemoveAll("./testdata/tmp/badger")
+	_ = sqlDB.Close()
+	_ = os.Remove("./testdata/tmp/cache.db")
 
 	os.Exit(code)
 }
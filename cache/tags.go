@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+// TaggableCache is implemented by caches that can invalidate entries by tag, for entries that
+// belong together but do not share a key prefix, such as everything cached about one user.
+// RedisCache and BadgerCache implement it
+type TaggableCache interface {
+	Cache
+	SetWithTags(key string, value interface{}, ttl int, tags ...string) error
+	FlushTags(tags ...string) error
+}
+
+// the tag set of every tag in KEYS gains ARGV[1], and lives at least as long as the entry:
+// ARGV[2] seconds, or forever when it is 0
+var redisTagScript = redis.NewScript(-1, `
+local ttl = tonumber(ARGV[2])
+for _, tag in ipairs(KEYS) do
+	local fresh = redis.call("EXISTS", tag) == 0
+	redis.call("SADD", tag, ARGV[1])
+	if ttl == 0 then
+		redis.call("PERSIST", tag)
+	else
+		local current = redis.call("TTL", tag)
+		if fresh or (current >= 0 and current < ttl) then
+			redis.call("EXPIRE", tag, ttl)
+		end
+	end
+end
+return 0`)
+
+func (c *RedisCache) tagKey(tag string) string {
+	return c.key("_tag:" + tag)
+}
+
+// SetWithTags stores value at key for ttl seconds, or without an expiry when ttl is 0, and
+// adds key to the set of every tag
+func (c *RedisCache) SetWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	var err error
+	if ttl > 0 {
+		err = c.Set(key, value, ttl)
+	} else {
+		err = c.Set(key, value)
+	}
+	if err != nil || len(tags) == 0 {
+		return err
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	// the script takes a variable number of keys, so their count comes first
+	args := make([]interface{}, 0, len(tags)+3)
+	args = append(args, len(tags))
+	for _, tag := range tags {
+		args = append(args, c.tagKey(tag))
+	}
+	args = append(args, c.key(key), ttl)
+
+	_, err = redisTagScript.Do(conn, args...)
+	return err
+}
+
+// FlushTags removes every entry stored with any of tags
+func (c *RedisCache) FlushTags(tags ...string) error {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	for _, tag := range tags {
+		keys, err := redis.Strings(conn.Do("SMEMBERS", c.tagKey(tag)))
+		if err != nil {
+			return err
+		}
+
+		args := make([]interface{}, 0, len(keys)+1)
+		for _, key := range keys {
+			args = append(args, key)
+		}
+		args = append(args, c.tagKey(tag))
+
+		if _, err := conn.Do("DEL", args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagIndexPrefix is the start of every index key for tag. An index key is the prefix followed
+// by the tagged key, and expires with the entry
+func tagIndexPrefix(tag string) []byte {
+	return []byte("_tag:" + tag + "\x00")
+}
+
+// SetWithTags stores value at key for ttl seconds, or without an expiry when ttl is 0, and
+// writes an index key for every tag in the same transaction
+func (b BadgerCache) SetWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	encoded, err := b.Serializer.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		entries := []*badger.Entry{badger.NewEntry([]byte(key), encoded)}
+		for _, tag := range tags {
+			entries = append(entries, badger.NewEntry(append(tagIndexPrefix(tag), key...), nil))
+		}
+
+		for _, e := range entries {
+			if ttl > 0 {
+				e = e.WithTTL(time.Second * time.Duration(ttl))
+			}
+			if err := txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FlushTags removes every entry stored with any of tags, together with the tags' index keys
+func (b BadgerCache) FlushTags(tags ...string) error {
+	for _, tag := range tags {
+		prefix := tagIndexPrefix(tag)
+
+		var keys [][]byte
+		err := b.Conn.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				index := it.Item().KeyCopy(nil)
+				keys = append(keys, index, index[len(prefix):])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// a single transaction can only hold so many writes, so delete in batches
+		wb := b.Conn.NewWriteBatch()
+		for _, key := range keys {
+			if err := wb.Delete(key); err != nil {
+				wb.Cancel()
+				return err
+			}
+		}
+		if err := wb.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestTaggableCache_FlushTags(t *testing.T) {
+	for name, c := range map[string]TaggableCache{"redis": &testRedisCache, "badger": testBadgerCache} {
+		_ = c.SetWithTags("profile:42", "profile", 60, "user:42")
+		_ = c.SetWithTags("orders:42", "orders", 0, "user:42", "orders")
+		_ = c.SetWithTags("orders:7", "orders", 60, "user:7", "orders")
+		_ = c.Set("untagged", "value")
+
+		err := c.FlushTags("user:42")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		for _, key := range []string{"profile:42", "orders:42"} {
+			inCache, _ := c.Has(key)
+			if inCache {
+				t.Errorf("%s: %s found in cache, and it should not be there", name, key)
+			}
+		}
+
+		for _, key := range []string{"orders:7", "untagged"} {
+			inCache, _ := c.Has(key)
+			if !inCache {
+				t.Errorf("%s: %s not found in cache, and it should be there", name, key)
+			}
+		}
+
+		err = c.FlushTags("orders", "not-a-tag")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		inCache, _ := c.Has("orders:7")
+		if inCache {
+			t.Errorf("%s: orders:7 found in cache, and it should not be there", name)
+		}
+	}
+}
+
+func TestRedisCache_TagExpiry(t *testing.T) {
+	conn := testRedisCache.Conn.Get()
+	defer conn.Close()
+
+	_ = testRedisCache.SetWithTags("short", "value", 10, "expiry")
+	_ = testRedisCache.SetWithTags("long", "value", 100, "expiry")
+	_ = testRedisCache.SetWithTags("shorter", "value", 5, "expiry")
+
+	ttl, _ := redis.Int(conn.Do("TTL", testRedisCache.tagKey("expiry")))
+	if ttl != 100 {
+		t.Errorf("expected the tag set to live as long as its longest entry; ttl is %d", ttl)
+	}
+
+	_ = testRedisCache.SetWithTags("forever", "value", 0, "expiry")
+	ttl, _ = redis.Int(conn.Do("TTL", testRedisCache.tagKey("expiry")))
+	if ttl != -1 {
+		t.Errorf("expected the tag set not to expire; ttl is %d", ttl)
+	}
+
+	_ = testRedisCache.FlushTags("expiry")
+}
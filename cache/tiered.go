@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// TieredCache keeps recently used values in an in-process L1 cache in front of a shared L2
+// cache, so most reads skip the network. L1 encodes values with the Serializer of L2, so a
+// value reads back the same from either tier. When Bus is set, every change is published on
+// Channel and every node listening evicts the key from its own L1
+type TieredCache struct {
+	// L1 is given the Serializer of L2 by NewTieredCache when it has none
+	L1 *MemoryCache
+	L2 Cache
+	// L1TTL is the longest a value is kept in L1, in seconds; zero means DefaultL1TTL. L1 does
+	// not know when a value read from L2 expires there, so this bounds how long a node may
+	// keep serving a value after it expired in L2
+	L1TTL    int
+	Bus      *redis.Pool
+	Channel  string
+	ErrorLog *log.Logger
+
+	nodeID string
+	mu     sync.Mutex
+	psc    *redis.PubSubConn
+	closed bool
+	done   chan struct{}
+}
+
+var errTieredClosed = errors.New("cache: tiered cache is closed")
+
+// DefaultL1TTL is the L1TTL, in seconds, of a TieredCache that does not set one
+const DefaultL1TTL = 60
+
+// DefaultInvalidationChannel is used when a TieredCache has a Bus but no Channel
+const DefaultInvalidationChannel = "ugo:cache:invalidate"
+
+// NewTieredCache returns a TieredCache over l1 and l2. bus may be nil for a single node
+func NewTieredCache(l1 *MemoryCache, l2 Cache, bus *redis.Pool) *TieredCache {
+	if l1.Serializer == nil {
+		l1.Serializer = serializerOf(l2)
+	}
+
+	return &TieredCache{
+		L1:      l1,
+		L2:      l2,
+		Bus:     bus,
+		Channel: DefaultInvalidationChannel,
+	}
+}
+
+func (t *TieredCache) Has(key string) (bool, error) {
+	if ok, _ := t.L1.Has(key); ok {
+		return true, nil
+	}
+	return t.L2.Has(key)
+}
+
+func (t *TieredCache) Get(key string) (interface{}, error) {
+	if value, err := t.L1.Get(key); err == nil {
+		return value, nil
+	}
+
+	value, err := t.L2.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// a value L1 cannot hold is still returned
+	t.l1Failed(key, t.L1.Set(key, value, t.l1TTL()))
+
+	return value, nil
+}
+
+func (t *TieredCache) Set(key string, value interface{}, expires ...int) error {
+	if err := t.L2.Set(key, value, expires...); err != nil {
+		return err
+	}
+
+	ttl := t.l1TTL()
+	if len(expires) > 0 && expires[0] > 0 && expires[0] < ttl {
+		ttl = expires[0]
+	}
+	t.l1Failed(key, t.L1.Set(key, value, ttl))
+
+	// other nodes drop the old value; this one already holds the new one
+	return t.publish("set", key)
+}
+
+// l1Failed logs a value L1 could not keep. Reads of key go to L2 until it is set again
+func (t *TieredCache) l1Failed(key string, err error) {
+	if err != nil && t.ErrorLog != nil {
+		t.ErrorLog.Printf("cache: L1 could not keep %s: %s", key, err)
+	}
+}
+
+func (t *TieredCache) l1TTL() int {
+	if t.L1TTL > 0 {
+		return t.L1TTL
+	}
+	return DefaultL1TTL
+}
+
+func (t *TieredCache) Forget(key string) error {
+	_ = t.L1.Forget(key)
+	if err := t.L2.Forget(key); err != nil {
+		return err
+	}
+	return t.publish("forget", key)
+}
+
+func (t *TieredCache) EmptyByMatch(prefix string) error {
+	_ = t.L1.EmptyByMatch(prefix)
+	if err := t.L2.EmptyByMatch(prefix); err != nil {
+		return err
+	}
+	return t.publish("match", prefix)
+}
+
+func (t *TieredCache) Empty() error {
+	_ = t.L1.Empty()
+	if err := t.L2.Empty(); err != nil {
+		return err
+	}
+	return t.publish("empty", "")
+}
+
+// invalidation messages are "<node id> <op> <key or prefix>"
+func (t *TieredCache) publish(op, arg string) error {
+	if t.Bus == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.nodeID == "" {
+		t.nodeID, _ = lockToken()
+	}
+	nodeID := t.nodeID
+	t.mu.Unlock()
+
+	conn := t.Bus.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", t.channel(), nodeID+" "+op+" "+arg)
+	return err
+}
+
+func (t *TieredCache) channel() string {
+	if t.Channel == "" {
+		return DefaultInvalidationChannel
+	}
+	return t.Channel
+}
+
+// apply evicts the L1 entries named by an invalidation message
+func (t *TieredCache) apply(message string) {
+	parts := strings.SplitN(message, " ", 3)
+	if len(parts) != 3 {
+		return
+	}
+
+	t.mu.Lock()
+	own := parts[0] == t.nodeID
+	t.mu.Unlock()
+
+	switch parts[1] {
+	case "set":
+		if !own {
+			_ = t.L1.Forget(parts[2])
+		}
+	case "forget":
+		_ = t.L1.Forget(parts[2])
+	case "match":
+		_ = t.L1.EmptyByMatch(parts[2])
+	case "empty":
+		_ = t.L1.Empty()
+	}
+}
+
+// Listen subscribes to invalidations from the other nodes until Close is called. It returns
+// once the first subscription is in place. A lost connection is retried every second, and
+// L1 is emptied when it comes back, since messages may have been missed
+func (t *TieredCache) Listen() error {
+	if t.Bus == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.nodeID == "" {
+		t.nodeID, _ = lockToken()
+	}
+	t.closed = false
+	t.done = make(chan struct{})
+	t.mu.Unlock()
+
+	psc, err := t.subscribe()
+	if err != nil {
+		return err
+	}
+
+	go t.listen(psc)
+	return nil
+}
+
+func (t *TieredCache) subscribe() (*redis.PubSubConn, error) {
+	psc := &redis.PubSubConn{Conn: t.Bus.Get()}
+	if err := psc.Subscribe(t.channel()); err != nil {
+		_ = psc.Close()
+		return nil, err
+	}
+
+	// wait for the confirmation, so messages published after Listen returns are received
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Subscription:
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.closed {
+				_ = psc.Close()
+				return nil, errTieredClosed
+			}
+			t.psc = psc
+			return psc, nil
+		case error:
+			_ = psc.Close()
+			return nil, v
+		}
+	}
+}
+
+func (t *TieredCache) listen(psc *redis.PubSubConn) {
+	defer close(t.done)
+
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			t.apply(string(v.Data))
+
+		case redis.Subscription:
+			// Close unsubscribed; the connection is closed here, as a pooled connection
+			// reads its remaining replies on close, which must not race with Receive
+			if v.Count == 0 {
+				t.mu.Lock()
+				_ = psc.Close()
+				t.mu.Unlock()
+				return
+			}
+
+		case error:
+			_ = psc.Close()
+
+			for {
+				t.mu.Lock()
+				closed := t.closed
+				t.mu.Unlock()
+				if closed {
+					return
+				}
+
+				if t.ErrorLog != nil {
+					t.ErrorLog.Println("cache invalidation subscription lost:", v)
+				}
+				time.Sleep(time.Second)
+
+				var err error
+				if psc, err = t.subscribe(); err == nil {
+					_ = t.L1.Empty()
+					break
+				}
+				v = err
+			}
+		}
+	}
+}
+
+// Close stops listening for invalidations
+func (t *TieredCache) Close() error {
+	t.mu.Lock()
+	psc, done := t.psc, t.done
+	t.closed = true
+	t.psc = nil
+
+	// listen closes the connection once the unsubscribe is confirmed. If the connection is
+	// broken instead, listen sees closed and stops. Writes to the connection are made with
+	// t.mu held, as listen may be closing it
+	if psc != nil {
+		_ = psc.Unsubscribe()
+	}
+	t.mu.Unlock()
+
+	if psc != nil {
+		<-done
+	}
+	return nil
+}
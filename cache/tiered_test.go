@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventually polls cond for up to a second, for changes that arrive over pub/sub
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func newTestTieredCache(t *testing.T) *TieredCache {
+	tc := NewTieredCache(NewMemoryCache(100, 0), &testRedisCache, testRedisCache.Conn)
+	tc.Channel = "test-ug:invalidate"
+	if err := tc.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = tc.Close()
+	})
+	return tc
+}
+
+func TestTieredCache_L1(t *testing.T) {
+	node := newTestTieredCache(t)
+
+	_ = node.Set("tiered", "bar")
+
+	// a change made straight to L2 is not seen while L1 holds the key
+	_ = testRedisCache.Set("tiered", "changed")
+
+	v, err := node.Get("tiered")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "bar" {
+		t.Errorf("expected bar from L1; got %v", v)
+	}
+
+	_ = node.L1.Forget("tiered")
+	v, _ = node.Get("tiered")
+	if v != "changed" {
+		t.Errorf("expected changed from L2; got %v", v)
+	}
+
+	inL1, _ := node.L1.Has("tiered")
+	if !inL1 {
+		t.Error("value read from L2 was not kept in L1")
+	}
+}
+
+func TestTieredCache_Invalidation(t *testing.T) {
+	a := newTestTieredCache(t)
+	b := newTestTieredCache(t)
+
+	_ = a.Set("user:1", "v1")
+	_ = a.Set("user:2", "v1")
+	_ = a.Set("post:1", "v1")
+
+	for _, key := range []string{"user:1", "user:2", "post:1"} {
+		_, _ = b.Get(key)
+	}
+
+	// a Set on one node drops the old value from the others, but not from itself
+	_ = a.Set("user:1", "v2")
+	if !eventually(func() bool {
+		v, _ := b.Get("user:1")
+		return v == "v2"
+	}) {
+		t.Error("b kept the old value of user:1")
+	}
+
+	inL1, _ := a.L1.Has("user:1")
+	if !inL1 {
+		t.Error("a dropped its own new value")
+	}
+
+	_ = a.Forget("user:1")
+	if !eventually(func() bool {
+		inL1, _ := b.L1.Has("user:1")
+		return !inL1
+	}) {
+		t.Error("Forget did not reach b")
+	}
+
+	_ = a.EmptyByMatch("user:")
+	if !eventually(func() bool {
+		inL1, _ := b.L1.Has("user:2")
+		return !inL1
+	}) {
+		t.Error("EmptyByMatch did not reach b")
+	}
+
+	inL1, _ = b.L1.Has("post:1")
+	if !inL1 {
+		t.Error("EmptyByMatch removed a key that does not match")
+	}
+
+	_ = a.Empty()
+	if !eventually(func() bool {
+		return b.L1.Len() == 0
+	}) {
+		t.Error("Empty did not reach b")
+	}
+}
+
+func TestTieredCache_L1Expiry(t *testing.T) {
+	tc := NewTieredCache(NewMemoryCache(100, 0), &testRedisCache, nil)
+
+	l1Expiry := func(key string) time.Time {
+		tc.L1.mu.Lock()
+		defer tc.L1.mu.Unlock()
+		return tc.L1.items[key].Value.(*memoryItem).expires
+	}
+
+	// a value read from L2 on a node that did not write it expires from L1 as well
+	_ = testRedisCache.Set("tiered-expiry", "bar", 3600)
+	if _, err := tc.Get("tiered-expiry"); err != nil {
+		t.Fatal(err)
+	}
+	expires := l1Expiry("tiered-expiry")
+	if expires.IsZero() || expires.After(time.Now().Add(DefaultL1TTL*time.Second)) {
+		t.Errorf("expected the L1 copy to expire within %ds; expires %v", DefaultL1TTL, expires)
+	}
+
+	// a shorter L2 expiry given to Set wins
+	_ = tc.Set("tiered-short", "bar", 5)
+	if expires := l1Expiry("tiered-short"); expires.After(time.Now().Add(5 * time.Second)) {
+		t.Errorf("expected the L1 copy to expire within 5s; expires %v", expires)
+	}
+}
+
+func TestTieredCache_L2Codec(t *testing.T) {
+	l2 := testRedisCache
+	l2.Serializer = &Serializer{Codec: JSONCodec}
+	tc := NewTieredCache(NewMemoryCache(100, 0), &l2, nil)
+
+	// a type gob cannot encode without gob.Register
+	value := map[string]interface{}{"id": 1, "tags": []string{"a", "b"}}
+	if err := tc.Set("tiered-json", value); err != nil {
+		t.Fatal(err)
+	}
+	if tc.L1.Len() != 1 {
+		t.Fatalf("expected the value in L1; L1 holds %d values", tc.L1.Len())
+	}
+
+	fromL1, err := tc.Get("tiered-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the value is read back from L1, as the change made straight to L2 shows
+	_ = l2.Set("tiered-json", "changed")
+	if v, _ := tc.Get("tiered-json"); v == "changed" {
+		t.Error("expected the value from L1")
+	}
+
+	_ = tc.L1.Forget("tiered-json")
+	_ = l2.Set("tiered-json", value)
+	fromL2, err := tc.Get("tiered-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromL1, fromL2) {
+		t.Errorf("expected the same value from either tier; got %#v and %#v", fromL1, fromL2)
+	}
+}
+
+func TestTieredCache_L1Failure(t *testing.T) {
+	var logged bytes.Buffer
+	tc := NewTieredCache(NewMemoryCache(100, 10), &testRedisCache, nil)
+	tc.ErrorLog = log.New(&logged, "", 0)
+
+	if err := tc.Set("tiered-large", strings.Repeat("x", 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.Get("tiered-large"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(logged.String(), "L1 could not keep tiered-large"); n != 2 {
+		t.Errorf("expected the set and the get to log the failure of L1; got %q", logged.String())
+	}
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Typed wraps a Cache so values go in and come out as T, without type assertions at the
+// call site. NewTyped registers T with gob, so structs can be cached without calling
+// gob.Register first
+type Typed[T any] struct {
+	Cache Cache
+}
+
+// NewTyped returns a Typed view of c for values of type T
+func NewTyped[T any](c Cache) *Typed[T] {
+	register[T]()
+	return &Typed[T]{Cache: c}
+}
+
+// register makes T known to gob so it can be stored in an Entry. gob sends the value behind a
+// pointer, so for a pointer type the element type is registered. Interface types cannot be
+// registered, and a type already registered under another name makes gob panic; both are
+// left as they are
+func register[T any]() {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() == reflect.Interface {
+		return
+	}
+
+	defer func() {
+		_ = recover()
+	}()
+	gob.Register(reflect.Zero(rt).Interface())
+}
+
+// Get returns the value stored at key. found is false, with a nil error, when the key is not
+// in the cache
+func (t *Typed[T]) Get(key string) (value T, found bool, err error) {
+	v, err := t.Cache.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return value, false, nil
+	} else if err != nil {
+		return value, false, err
+	}
+
+	value, err = t.convert(key, v)
+	if err != nil {
+		return value, false, err
+	}
+
+	return value, true, nil
+}
+
+// convert turns a value read from the cache back into a T
+func (t *Typed[T]) convert(key string, v interface{}) (value T, err error) {
+	if v == nil {
+		return value, nil
+	}
+
+	if value, ok := v.(T); ok {
+		return value, nil
+	}
+
+	// a *X comes back from gob as an X
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	if rv := reflect.ValueOf(v); rt.Kind() == reflect.Ptr && rv.Type() == rt.Elem() {
+		p := reflect.New(rt.Elem())
+		p.Elem().Set(rv)
+		return p.Interface().(T), nil
+	}
+
+	// json and msgpack decode into maps, slices and numbers rather than T
+	if generic(v) {
+		if data, err := json.Marshal(v); err == nil {
+			if err := json.Unmarshal(data, &value); err == nil {
+				return value, nil
+			}
+		}
+	}
+
+	return value, fmt.Errorf("cache: value at %q is %T, not %T", key, v, value)
+}
+
+// Set stores value at key. expires is the lifetime in seconds, as for Cache.Set
+func (t *Typed[T]) Set(key string, value T, expires ...int) error {
+	return t.Cache.Set(key, value, expires...)
+}
+
+// Forget removes key from the cache
+func (t *Typed[T]) Forget(key string) error {
+	return t.Cache.Forget(key)
+}
+
+// Remember returns the value at key, or calls fn and stores its result for ttl seconds when
+// the key is missing. A ttl of zero stores the value without an expiry. fn's error is returned
+// as is and nothing is stored; if storing fails, the loaded value is returned with the error.
+// opts are passed on to the cache's Remember
+func (t *Typed[T]) Remember(key string, ttl int, fn func() (T, error), opts ...RememberOption) (T, error) {
+	v, err := Remember(t.Cache, key, ttl, func() (interface{}, error) {
+		return fn()
+	}, opts...)
+	if err != nil {
+		var value T
+		if v != nil {
+			value, _ = t.convert(key, v)
+		}
+		return value, err
+	}
+
+	return t.convert(key, v)
+}
+
+// generic reports whether v is one of the types a codec without type information decodes into
+func generic(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, float64, float32,
+		int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
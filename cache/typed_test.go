@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+type typedUser struct {
+	ID    int
+	Name  string
+	Roles []string
+}
+
+func TestTyped_GetSet(t *testing.T) {
+	caches := map[string]Cache{
+		"memory": testMemoryCache,
+		"redis":  &testRedisCache,
+		"badger": testBadgerCache,
+		"sql":    &testDatabaseCache,
+	}
+
+	for name, c := range caches {
+		users := NewTyped[typedUser](c)
+
+		_ = users.Forget("typed-user")
+		_, found, err := users.Get("typed-user")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if found {
+			t.Errorf("%s: typed-user found in cache and it shouldn`t be there!", name)
+		}
+
+		want := typedUser{ID: 1, Name: "Ada", Roles: []string{"admin"}}
+		err = users.Set("typed-user", want)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+
+		got, found, err := users.Get("typed-user")
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		if !found || got.Name != want.Name || len(got.Roles) != 1 {
+			t.Errorf("%s: expected %v; got %v", name, want, got)
+		}
+	}
+}
+
+func TestTyped_Pointer(t *testing.T) {
+	users := NewTyped[*typedUser](testMemoryCache)
+
+	err := users.Set("typed-pointer", &typedUser{ID: 2, Name: "Grace"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	got, found, err := users.Get("typed-pointer")
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || got == nil || got.Name != "Grace" {
+		t.Errorf("expected Grace; got %v", got)
+	}
+}
+
+func TestTyped_WrongType(t *testing.T) {
+	_ = testMemoryCache.Set("typed-wrong", "a string")
+
+	users := NewTyped[typedUser](testMemoryCache)
+	_, _, err := users.Get("typed-wrong")
+	if err == nil {
+		t.Error("expected an error for a value of the wrong type")
+	}
+}
+
+func TestTyped_Remember(t *testing.T) {
+	counts := NewTyped[int](testMemoryCache)
+	_ = counts.Forget("typed-remember")
+
+	calls := 0
+	load := func() (int, error) {
+		calls++
+		return 42, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		v, err := counts.Remember("typed-remember", 60, load)
+		if err != nil {
+			t.Error(err)
+		}
+		if v != 42 {
+			t.Errorf("expected 42; got %d", v)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the loader to be called once; called %d times", calls)
+	}
+
+	loadErr := errors.New("load failed")
+	_, err := counts.Remember("typed-remember-err", 60, func() (int, error) {
+		return 0, loadErr
+	})
+	if err != loadErr {
+		t.Errorf("expected the loader error; got %v", err)
+	}
+
+	inCache, _ := testMemoryCache.Has("typed-remember-err")
+	if inCache {
+		t.Error("value stored even though the loader failed")
+	}
+}
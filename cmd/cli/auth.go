@@ -8,7 +8,7 @@ import (
 
 func doAuth() error {
 	// migrations
-	dbType := ug.DB.DataType
+	dbType := migrationType()
 	fileName := fmt.Sprintf("%d_create_auth_tables", time.Now().UnixMicro())
 
 	upFile := ug.RootPath + "/migrations/" + fileName + ".up.sql"
@@ -30,6 +30,11 @@ func doAuth() error {
 	}
 	// run migrations
 
+	err = ensureModelsFile()
+	if err != nil {
+		return err
+	}
+
 	err = copyFileFromTemplate("templates/data/user.go.txt", ug.RootPath+"/data/user.go")
 	if err != nil {
 		return err
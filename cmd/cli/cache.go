@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joefazee/ugo/cache"
+)
+
+func doCacheTable() error {
+
+	dbType := migrationType()
+
+	fileName := fmt.Sprintf("%d_create_cache_table", time.Now().UnixMicro())
+
+	upFile := ug.RootPath + "/migrations/" + fileName + "." + dbType + ".up.sql"
+	downFile := ug.RootPath + "/migrations/" + fileName + "." + dbType + ".down.sql"
+
+	err := copyFileFromTemplate("templates/migrations/"+dbType+"_cache.sql", upFile)
+	if err != nil {
+		return err
+	}
+
+	err = copyDataToFile([]byte("drop table cache"), downFile)
+	if err != nil {
+		return err
+	}
+
+	err = doMigrate("up", "")
+
+	return err
+}
+
+// openCache opens the cache of the application; tests replace it
+var openCache = func() (cache.Cache, func() error, error) {
+	return ug.OpenCache()
+}
+
+func doCacheStats() error {
+	c, closeCache, err := openCache()
+	if err == nil {
+		defer closeCache()
+
+		if counter, ok := c.(cache.CountableCache); ok {
+			keys, err := counter.Count()
+			if err != nil {
+				return err
+			}
+			color.Yellow("Cache: %s, keys: %d", ug.Config.Cache, keys)
+		}
+	} else {
+		color.Yellow("Cache: %s (%v)", ug.Config.Cache, err)
+	}
+
+	if ug.Config.CacheMetrics == "" {
+		color.Yellow("Set CACHE_METRICS_PATH to collect hit, miss and latency statistics")
+		return nil
+	}
+
+	stats, err := fetchCacheStats()
+	if err != nil {
+		return fmt.Errorf("could not read statistics from the running application: %w", err)
+	}
+
+	color.Yellow("Hits: %d, misses: %d, hit ratio: %.1f%%, errors: %d",
+		stats.Hits, stats.Misses, stats.HitRatio*100, stats.Errors)
+
+	ops := make([]string, 0, len(stats.Operations))
+	for op := range stats.Operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	for _, op := range ops {
+		s := stats.Operations[op]
+		var avg time.Duration
+		if s.Latency.Count > 0 {
+			avg = time.Duration(s.Latency.Sum / float64(s.Latency.Count) * float64(time.Second))
+		}
+		color.Yellow("  %-15s calls: %d, errors: %d, average: %s", op, s.Calls, s.Errors, avg)
+	}
+
+	if stats.PayloadSizes.Count > 0 {
+		color.Yellow("Average payload: %.0f bytes", stats.PayloadSizes.Sum/float64(stats.PayloadSizes.Count))
+	}
+
+	return nil
+}
+
+// fetchCacheStats reads the statistics of the running application from its metrics endpoint
+func fetchCacheStats() (*cache.Stats, error) {
+	protocol := "http"
+	if ug.Config.Server.TLSCert != "" {
+		protocol = "https"
+	}
+	url := fmt.Sprintf("%s://%s:%s%s", protocol, ug.Config.Server.Name, ug.Config.Server.Port, ug.Config.CacheMetrics)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ug.Config.CacheMetricsToken)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	var stats cache.Stats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func doCacheClear(prefix string) error {
+	c, closeCache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+
+	if prefix == "" {
+		return c.Empty()
+	}
+	return c.EmptyByMatch(prefix)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/joefazee/ugo/cache"
+)
+
+// pagingConn serves SCAN two keys at a time, as redis does for a large keyspace, where
+// miniredis returns every key at once
+type pagingConn struct {
+	redis.Conn
+	scans *int
+}
+
+func (c pagingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "SCAN" {
+		return c.Conn.Do(cmd, args...)
+	}
+
+	*c.scans++
+	if *c.scans > 1000 {
+		return nil, errors.New("SCAN called 1000 times; the cursor is not advancing")
+	}
+
+	cursor, _ := strconv.Atoi(fmt.Sprint(args[0]))
+	all, err := redis.Values(c.Conn.Do("SCAN", append([]interface{}{0}, args[1:]...)...))
+	if err != nil {
+		return nil, err
+	}
+	keys, _ := redis.Strings(all[1], nil)
+	sort.Strings(keys)
+
+	end, next := cursor+2, cursor+2
+	if end >= len(keys) {
+		end, next = len(keys), 0
+	}
+
+	page := make([]interface{}, 0, 2)
+	for _, k := range keys[cursor:end] {
+		page = append(page, []byte(k))
+	}
+	return []interface{}{[]byte(strconv.Itoa(next)), page}, nil
+}
+
+func TestCacheCommands_ScanPages(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var scans int
+	c := &cache.RedisCache{
+		Prefix: "cli",
+		Conn: &redis.Pool{Dial: func() (redis.Conn, error) {
+			conn, err := redis.Dial("tcp", s.Addr())
+			if err != nil {
+				return nil, err
+			}
+			return pagingConn{Conn: conn, scans: &scans}, nil
+		}},
+	}
+
+	saved := openCache
+	defer func() { openCache = saved }()
+	openCache = func() (cache.Cache, func() error, error) {
+		return c, func() error { return nil }, nil
+	}
+
+	fill := func() {
+		for i := 0; i < 11; i++ {
+			_ = c.Set(fmt.Sprintf("users:%d", i), i)
+		}
+		_ = c.Set("posts:1", 1)
+		scans = 0
+	}
+
+	fill()
+	if err := doCacheStats(); err != nil {
+		t.Fatal(err)
+	}
+	if scans < 2 {
+		t.Errorf("expected cache:stats to scan several pages; got %d", scans)
+	}
+
+	fill()
+	if err := doCacheClear("users:"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Count(); n != 1 {
+		t.Errorf("expected only posts:1 to be left; got %d keys", n)
+	}
+
+	fill()
+	if err := doCacheClear(""); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Count(); n != 0 {
+		t.Errorf("expected an empty cache; got %d keys", n)
+	}
+}
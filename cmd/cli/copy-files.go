@@ -3,8 +3,10 @@ package main
 import (
 	"embed"
 	"errors"
+	"github.com/fatih/color"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
 //go:embed templates
@@ -28,6 +30,43 @@ func copyFileFromTemplate(fromFile string, toFile string) error {
 	return nil
 }
 
+// ensureModelsFile creates data/models.go, which sets up upper for postgres or mysql, unless
+// the application already has one. A sqlite application also gets data/sqlite.go, which sets
+// up upper's sqlite adapter, kept apart as it imports the cgo driver github.com/mattn/go-sqlite3
+func ensureModelsFile() error {
+	fileName := ug.RootPath + "/data/models.go"
+	if !fileExists(fileName) {
+		err := copyFileFromTemplate("templates/data/models.go.txt", fileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	sqliteFile := ug.RootPath + "/data/sqlite.go"
+	if ug.Config.Database.Type != "sqlite" || fileExists(sqliteFile) {
+		return nil
+	}
+
+	// the models.go of the skeleton application predates sqlite and must be changed by hand
+	models, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(models), "openSQLite") {
+		color.Yellow(sqliteModelsNotice)
+		return nil
+	}
+
+	return copyFileFromTemplate("templates/data/sqlite.go.txt", sqliteFile)
+}
+
+const sqliteModelsNotice = `data/models.go does not set up upper for sqlite. In New, add
+	case "sqlite":
+		upper, _ = sqlite.New(databasePool)
+importing github.com/upper/db/v4/adapter/sqlite. That adapter imports the cgo driver
+github.com/mattn/go-sqlite3 but uses the pool ugo opens with modernc.org/sqlite, so
+CGO_ENABLED=0 builds leave the cgo driver out`
+
 func copyDataToFile(data []byte, file string) error {
 	err := ioutil.WriteFile(file, data, 0644 )
 
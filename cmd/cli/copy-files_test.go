@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureModelsFile(t *testing.T) {
+	root, dbType := ug.RootPath, ug.Config.Database.Type
+	defer func() { ug.RootPath, ug.Config.Database.Type = root, dbType }()
+
+	tests := []struct {
+		name, dbType, models string
+		sqliteFile           bool
+	}{
+		{"postgres", "postgres", "", false},
+		{"sqlite", "sqlite", "", true},
+		{"sqlite_skeleton_models", "sqlite", "package data\n", false},
+	}
+
+	for _, tt := range tests {
+		ug.RootPath = t.TempDir()
+		ug.Config.Database.Type = tt.dbType
+		if err := os.Mkdir(filepath.Join(ug.RootPath, "data"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if tt.models != "" {
+			if err := os.WriteFile(filepath.Join(ug.RootPath, "data", "models.go"), []byte(tt.models), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := ensureModelsFile(); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+
+		if !fileExists(filepath.Join(ug.RootPath, "data", "models.go")) {
+			t.Errorf("%s: expected data/models.go", tt.name)
+		}
+		if got := fileExists(filepath.Join(ug.RootPath, "data", "sqlite.go")); got != tt.sqliteFile {
+			t.Errorf("%s: expected data/sqlite.go to exist to be %v", tt.name, tt.sqliteFile)
+		}
+
+		// running it again, as make model does for every model, changes nothing
+		if err := ensureModelsFile(); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"github.com/fatih/color"
+	"github.com/go-sql-driver/mysql"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,32 +14,78 @@ const (
 	oldImport = "github.com/joefazee/ladiwork"
 )
 
+// migrateMySQLDSN turns a go-sql-driver/mysql DSN into the URL migrate expects
+func migrateMySQLDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	// migrate unescapes the user and password, so escape them before handing over the DSN
+	cfg.User = url.QueryEscape(cfg.User)
+	cfg.Passwd = url.QueryEscape(cfg.Passwd)
+
+	// migrate takes any tls value other than a boolean or skip-verify for the name of a
+	// certificate it must load from x-tls-ca. preferred never guaranteed encryption, so
+	// migrations connect without it
+	if cfg.TLSConfig == "preferred" {
+		cfg.TLSConfig = "false"
+	}
+
+	return "mysql://" + cfg.FormatDSN(), nil
+}
+
 func getDSN() string {
 	dbType := ug.DB.DataType
+	db := ug.Config.Database
 
-	if dbType == "pgx" || dbType == "postgres" {
+	if dbType == "pgx" || dbType == "postgres" || dbType == "postgresql" {
 		var dsn string
-		if os.Getenv("DATABASE_PASS") != "" {
+		if db.Pass != "" {
 			dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-				os.Getenv("DATABASE_USER"),
-				os.Getenv("DATABASE_PASS"),
-				os.Getenv("DATABASE_HOST"),
-				os.Getenv("DATABASE_PORT"),
-				os.Getenv("DATABASE_NAME"),
-				os.Getenv("DATABASE_SSL_MODE"))
+				db.User,
+				db.Pass,
+				db.Host,
+				db.Port,
+				db.Name,
+				db.SSLMode)
 		} else {
 			dsn = fmt.Sprintf("postgres://%s@%s:%s/%s?sslmode=%s",
-				os.Getenv("DATABASE_USER"),
-				os.Getenv("DATABASE_HOST"),
-				os.Getenv("DATABASE_PORT"),
-				os.Getenv("DATABASE_NAME"),
-				os.Getenv("DATABASE_SSL_MODE"))
+				db.User,
+				db.Host,
+				db.Port,
+				db.Name,
+				db.SSLMode)
 		}
 
 		return dsn
 	}
 
-	return "mysql://" + ug.BuildDSN()
+	if dbType == "mysql" || dbType == "mariadb" {
+		dsn, err := migrateMySQLDSN(ug.BuildDSN())
+		if err != nil {
+			exitGracefully(err)
+		}
+		return dsn
+	}
+
+	if dbType == "sqlite" {
+		return strings.Replace(ug.BuildDSN(), "file:", "sqlite://", 1)
+	}
+
+	return ""
+}
+
+// migrationType returns the database type used to name migration files and templates
+func migrationType() string {
+	switch dbType := strings.ToLower(ug.DB.DataType); dbType {
+	case "mariadb":
+		return "mysql"
+	case "postgresql", "pgx":
+		return "postgres"
+	default:
+		return dbType
+	}
 }
 
 func showHelp() {
@@ -54,7 +102,11 @@ func showHelp() {
  make handler <name>	- creates a stub handler in the handlers directory
  make model <name>		- creates a new model in the data directory	
  make session 			- creates a table in the database as a session store
+ make cache-table 		- creates a table in the database for the database cache driver
+ make sessions-page 	- creates a page where users can see and log out the devices they are logged in on
  make mail <name>		- create two starter mail templates in the mail directory
+ cache:stats            - show the number of cache keys, and hit and miss statistics when CACHE_METRICS_PATH is set
+ cache:clear [prefix]   - remove every cache key, or only the keys starting with prefix
 `)
 
 }
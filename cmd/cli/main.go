@@ -18,7 +18,10 @@ func main() {
 		exitGracefully(err)
 	}
 
-	setup(arg1, arg2)
+	err = setup(arg1, arg2)
+	if err != nil {
+		exitGracefully(err)
+	}
 
 	switch arg1 {
 	case "help":
@@ -53,6 +56,23 @@ func main() {
 			exitGracefully(err)
 		}
 
+	case "cache:stats":
+		err = doCacheStats()
+		if err != nil {
+			exitGracefully(err)
+		}
+
+	case "cache:clear":
+		err = doCacheClear(arg2)
+		if err != nil {
+			exitGracefully(err)
+		}
+
+		message = "Cache cleared!"
+		if arg2 != "" {
+			message = "Cleared cache keys starting with " + arg2
+		}
+
 	default:
 		showHelp()
 	}
@@ -20,7 +20,7 @@ func doMake(arg2 string, arg3 string) error {
 		rnd := ug.GenerateRandomString(32)
 		color.Yellow("32 character encryption key %s", rnd)
 	case "migration":
-		dbType := ug.DB.DataType
+		dbType := migrationType()
 		if arg3 == "" {
 			exitGracefully(errors.New("you must give the migration a name"))
 		}
@@ -75,6 +75,11 @@ func doMake(arg2 string, arg3 string) error {
 		model = strings.ReplaceAll(model, "$MODELNAME$", strcase.ToCamel(modelName))
 		model = strings.ReplaceAll(model, "$TABLENAME$", tableName)
 
+		err = ensureModelsFile()
+		if err != nil {
+			exitGracefully(err)
+		}
+
 		err = copyDataToFile([]byte(model), fileName)
 		if err != nil {
 			exitGracefully(err)
@@ -107,6 +112,18 @@ func doMake(arg2 string, arg3 string) error {
 			exitGracefully(err)
 		}
 
+	case "sessions-page":
+		err := doSessionsPage()
+		if err != nil {
+			exitGracefully(err)
+		}
+
+	case "cache-table":
+		err := doCacheTable()
+		if err != nil {
+			exitGracefully(err)
+		}
+
 	case "mail":
 		if arg3 == "" {
 			exitGracefully(errors.New("you must provide a name for the mail template"))
@@ -2,21 +2,13 @@ package main
 
 import (
 	"fmt"
-	"strings"
+	"github.com/fatih/color"
 	"time"
 )
 
 func doSessionTable() error {
 
-	dbType := strings.ToLower(ug.DB.DataType)
-
-	if dbType == "mariadb" {
-		dbType = "mysql"
-	}
-
-	if dbType == "postgresql" {
-		dbType = "postgres"
-	}
+	dbType := migrationType()
 
 	fileName := fmt.Sprintf("%d_create_sessions_table", time.Now().UnixMicro())
 
@@ -37,3 +29,32 @@ func doSessionTable() error {
 
 	return err
 }
+
+// doSessionsPage scaffolds the handlers and view of the "active sessions" page
+func doSessionsPage() error {
+	err := copyFileFromTemplate("templates/handlers/session-handlers.go.txt", ug.RootPath+"/handlers/session-handlers.go")
+	if err != nil {
+		return err
+	}
+
+	err = copyFileFromTemplate("templates/views/sessions.page.jet", ug.RootPath+"/views/sessions.page.jet")
+	if err != nil {
+		return err
+	}
+
+	color.Yellow(" - session handlers and view created")
+	color.Yellow("")
+	color.Yellow("Add the routes for the page to your router:")
+	color.Yellow("")
+	color.Yellow(`   mux.Get("/users/sessions", h.ActiveSessions)`)
+	color.Yellow(`   mux.Post("/users/sessions/revoke", h.RevokeSession)`)
+	color.Yellow(`   mux.Post("/users/sessions/revoke-others", h.RevokeOtherSessions)`)
+	color.Yellow("")
+	color.Yellow("If you use remember tokens, delete them when a device is logged out:")
+	color.Yellow("")
+	color.Yellow("   app.Sessions.OnRevoke = h.ForgetRememberToken")
+	color.Yellow("")
+	color.Yellow("Sessions kept in cookies (SESSION_TYPE=cookie) cannot be listed or revoked")
+
+	return nil
+}
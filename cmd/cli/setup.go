@@ -1,26 +1,49 @@
 package main
 
 import (
-	"errors"
-	"github.com/joho/godotenv"
 	"os"
+
+	"github.com/joefazee/ugo"
 )
 
-func setup(arg1, arg2 string) {
+// commandSettings are the settings a command uses, and the only ones checked before it runs.
+// A name ending in _ covers every setting that starts with it
+var commandSettings = map[string][]string{
+	"migrate":          {"DATABASE_"},
+	"make migration":   {"DATABASE_TYPE"},
+	"make auth":        {"DATABASE_TYPE"},
+	"make model":       {"DATABASE_TYPE"},
+	"make session":     {"DATABASE_TYPE", "SESSION_TYPE"},
+	"make cache-table": {"DATABASE_TYPE"},
+	"cache:clear":      {"CACHE", "CACHE_", "REDIS_", "DATABASE_"},
+	"cache:stats":      {"CACHE", "CACHE_", "REDIS_", "DATABASE_", "SERVER_NAME", "PORT", "TLS_CERT"},
+}
 
-	if arg1 != "new" && arg1 != "help" && arg1 != "version" {
-		err := godotenv.Load()
-		if err != nil {
-			exitGracefully(errors.New("error loading .env file"))
-		}
+func setup(arg1, arg2 string) error {
+	// these commands do not run inside an application, or need nothing from it
+	switch {
+	case arg1 == "new", arg1 == "help", arg1 == "version", arg1 == "make" && arg2 == "key":
+		return nil
+	}
 
-		path, err := os.Getwd()
-		if err != nil {
-			exitGracefully(err)
-		}
+	path, err := os.Getwd()
+	if err != nil {
+		return err
+	}
 
-		ug.RootPath = path
-		ug.DB.DataType = os.Getenv("DATABASE_TYPE")
+	settings := commandSettings[arg1]
+	if arg1 == "make" {
+		settings = commandSettings[arg1+" "+arg2]
 	}
 
+	cfg, err := ugo.LoadConfigFor(path, settings...)
+	if err != nil {
+		return err
+	}
+
+	ug.Config = *cfg
+	ug.RootPath = path
+	ug.DB.DataType = cfg.Database.Type
+
+	return nil
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetup_CommandSettings(t *testing.T) {
+	cfg, root, dataType := ug.Config, ug.RootPath, ug.DB.DataType
+	defer func() { ug.Config, ug.RootPath, ug.DB.DataType = cfg, root, dataType }()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	// the variables .env puts in the environment are removed after every case
+	keys := []string{"KEY", "DATABASE_TYPE", "DATABASE_NAME", "CACHE"}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		_ = os.Unsetenv(key)
+	}
+
+	tests := []struct {
+		name, env, arg1, arg2 string
+		problem               string
+	}{
+		{"make_key_empty_env", "", "make", "key", ""},
+		{"make_key_invalid_env", "CACHE=memcached\n", "make", "key", ""},
+		{"make_handler_without_key", "", "make", "handler", ""},
+		{"migrate", "KEY=short\nDATABASE_TYPE=postgres\n", "migrate", "up", "DATABASE_HOST is required"},
+		{"migrate_ignores_key", "KEY=short\nDATABASE_TYPE=sqlite\nDATABASE_NAME=app.db\n", "migrate", "up", ""},
+		{"make_migration_ignores_cache", "CACHE=memcached\nDATABASE_TYPE=postgres\n", "make", "migration", ""},
+		{"cache_clear", "CACHE=memcached\n", "cache:clear", "", "CACHE must be one of"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(dir+"/.env", []byte(tt.env), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		err := setup(tt.arg1, tt.arg2)
+		switch {
+		case tt.problem == "" && err != nil:
+			t.Errorf("%s: %s", tt.name, err)
+		case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+			t.Errorf("%s: expected %q; got %v", tt.name, tt.problem, err)
+		}
+
+		for _, key := range keys {
+			_ = os.Unsetenv(key)
+		}
+	}
+
+	if err := doMake("key", ""); err != nil {
+		t.Errorf("make key: %s", err)
+	}
+}
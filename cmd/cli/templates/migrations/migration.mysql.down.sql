@@ -0,0 +1 @@
+-- drop table some_table;
\ No newline at end of file
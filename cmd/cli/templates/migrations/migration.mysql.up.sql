@@ -0,0 +1,6 @@
+-- CREATE TABLE some_table (
+--     id int unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY,
+--     some_field VARCHAR ( 255 ) NOT NULL,
+--     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+--     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+-- ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
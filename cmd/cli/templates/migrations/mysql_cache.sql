@@ -0,0 +1,7 @@
+CREATE TABLE cache (
+      cache_key VARCHAR(255) PRIMARY KEY,
+      value LONGBLOB NOT NULL,
+      expiry BIGINT NULL
+);
+
+CREATE INDEX cache_expiry_idx ON cache (expiry);
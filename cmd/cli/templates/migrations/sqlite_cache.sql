@@ -0,0 +1,7 @@
+CREATE TABLE cache (
+      cache_key TEXT PRIMARY KEY,
+      value BLOB NOT NULL,
+      expiry INTEGER NULL
+);
+
+CREATE INDEX cache_expiry_idx ON cache (expiry);
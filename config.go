@@ -0,0 +1,537 @@
+package ugo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds every setting ugo reads at start up. Each field is filled from the first of, in order:
+// the real environment, .env.<APP_ENV>, .env, config.yaml (or config.toml) and the field's default.
+//
+// Fields are described with struct tags:
+//
+//	env      the key(s) to read, comma separated; later keys are accepted for backwards compatibility
+//	default  the value used when no key is set
+//	required the key must be set to a non-empty value
+//	options  the comma separated list of accepted values
+//	unit     for durations, the unit of a plain number such as COOKIE_LIFETIME=60; for sizes, B
+//	         accepts KB, MB and GB suffixes, such as CACHE_MAX_BYTES=64MB
+type Config struct {
+	AppName           string        `env:"APP_NAME"`
+	Env               string        `env:"APP_ENV" default:"development"`
+	Debug             bool          `env:"DEBUG" default:"false"`
+	Key               string        `env:"KEY" required:"true"`
+	PreviousKeys      []string      `env:"PREVIOUS_KEYS"`
+	Renderer          string        `env:"RENDERER" default:"jet" options:"jet,go"`
+	Cache             string        `env:"CACHE" default:"memory" options:"memory,redis,badger,database"`
+	CacheMaxEntries   int           `env:"CACHE_MAX_ENTRIES" default:"10000"`
+	CacheMaxBytes     int           `env:"CACHE_MAX_BYTES" default:"64MB" unit:"B"`
+	CacheCodec        string        `env:"CACHE_CODEC" default:"gob" options:"gob,json,msgpack"`
+	CacheCompress     string        `env:"CACHE_COMPRESSION" default:"none" options:"none,zstd,snappy"`
+	CacheCompressAt   int           `env:"CACHE_COMPRESSION_THRESHOLD" default:"1024"`
+	CacheMetrics      string        `env:"CACHE_METRICS_PATH"`
+	CacheMetricsToken string        `env:"CACHE_METRICS_TOKEN"`
+	SessionType       string        `env:"SESSION_TYPE" options:"cookie,redis,badger,mysql,mariadb,postgres,postgresql,sqlite"`
+	ShutdownTimeout   time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30" unit:"s"`
+	Server            ServerConfig
+	Database          DatabaseConfig
+	Redis             RedisConfig
+	Cookie            CookieConfig
+	Mail              MailConfig
+	Locale            LocaleConfig
+}
+
+type ServerConfig struct {
+	Name            string `env:"SERVER_NAME" default:"localhost"`
+	Port            string `env:"PORT" default:"4000"`
+	Secure          bool   `env:"SECURE" default:"true"`
+	TLSCert         string `env:"TLS_CERT"`
+	TLSKey          string `env:"TLS_KEY"`
+	TLSRedirectPort string `env:"TLS_REDIRECT_PORT"`
+}
+
+type DatabaseConfig struct {
+	Type      string `env:"DATABASE_TYPE" options:"postgres,postgresql,mysql,mariadb,sqlite"`
+	Host      string `env:"DATABASE_HOST"`
+	Port      string `env:"DATABASE_PORT"`
+	User      string `env:"DATABASE_USER"`
+	Pass      string `env:"DATABASE_PASS"`
+	Name      string `env:"DATABASE_NAME"`
+	SSLMode   string `env:"DATABASE_SSL_MODE"`
+	Timezone  string `env:"DATABASE_TIMEZONE" default:"UTC"`
+	Collation string `env:"DATABASE_COLLATION" default:"utf8mb4_unicode_ci"`
+
+	MaxOpenConns    int           `env:"DATABASE_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `env:"DATABASE_MAX_IDLE_CONNS" default:"25"`
+	ConnMaxLifetime time.Duration `env:"DATABASE_CONN_MAX_LIFETIME" default:"5m" unit:"s"`
+	ConnMaxIdleTime time.Duration `env:"DATABASE_CONN_MAX_IDLE_TIME" default:"5m" unit:"s"`
+
+	// Replicas are read-only copies of the primary, as host or host:port, sharing its credentials
+	Replicas             []string      `env:"DATABASE_REPLICAS"`
+	ReplicaCheckInterval time.Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL" default:"10s" unit:"s"`
+}
+
+type RedisConfig struct {
+	Host     string `env:"REDIS_HOST"`
+	Password string `env:"REDIS_PASSWORD"`
+	Prefix   string `env:"REDIS_PREFIX"`
+}
+
+type CookieConfig struct {
+	Name     string        `env:"COOKIE_NAME"`
+	Lifetime time.Duration `env:"COOKIE_LIFETIME" default:"60" unit:"m"`
+	Persist  bool          `env:"COOKIE_PERSIST,COOKIE_PERSISTS" default:"true"`
+	Secure   bool          `env:"COOKIE_SECURE" default:"false"`
+	Domain   string        `env:"COOKIE_DOMAIN"`
+	SameSite string        `env:"COOKIE_SAME_SITE" default:"lax" options:"lax,strict,none"`
+
+	// IdleTimeout ends a session that has not been used for this long; zero never does
+	IdleTimeout time.Duration `env:"SESSION_IDLE_TIMEOUT" default:"0" unit:"m"`
+	// AbsoluteTimeout ends a session this long after it started, however much it is used.
+	// It defaults to the cookie lifetime
+	AbsoluteTimeout time.Duration `env:"SESSION_ABSOLUTE_TIMEOUT" unit:"m"`
+}
+
+type MailConfig struct {
+	Domain      string `env:"MAIL_DOMAIN"`
+	Host        string `env:"SMTP_HOST"`
+	Port        int    `env:"SMTP_PORT" default:"1025"`
+	Username    string `env:"SMTP_USERNAME"`
+	Password    string `env:"SMTP_PASSWORD"`
+	Encryption  string `env:"SMTP_ENCRYPTION" options:"tls,ssl,none"`
+	FromName    string `env:"FROM_NAME"`
+	FromAddress string `env:"FROM_ADDRESS,SMTP_FROM"`
+	API         string `env:"MAILER_API" options:"smtp,mailgun,sparkpost,sendgrid"`
+	APIKey      string `env:"MAILER_KEY"`
+	APIURL      string `env:"MAILER_URL"`
+}
+
+type LocaleConfig struct {
+	Default string `env:"LOCALE" default:"en"`
+	Cookie  string `env:"LOCALE_COOKIE" default:"lang"`
+	// URLPrefix lets a path such as /fr/about pick the language of the request
+	URLPrefix bool `env:"LOCALE_URL_PREFIX" default:"true"`
+}
+
+// sessionLifetime is the absolute timeout of a session
+func (c CookieConfig) sessionLifetime() time.Duration {
+	if c.AbsoluteTimeout > 0 {
+		return c.AbsoluteTimeout
+	}
+	return c.Lifetime
+}
+
+// ConfigError lists every missing or invalid setting found while loading the configuration
+type ConfigError struct {
+	Problems []string
+	// settings holds the setting each problem is about
+	settings []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration:\n  - %s", strings.Join(e.Problems, "\n  - "))
+}
+
+func (e *ConfigError) add(setting, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+	e.settings = append(e.settings, setting)
+}
+
+// about returns the problems with the settings named. A name ending in _ names every
+// setting that starts with it
+func (e *ConfigError) about(settings []string) *ConfigError {
+	found := &ConfigError{}
+	for i, setting := range e.settings {
+		for _, name := range settings {
+			if setting == name || (strings.HasSuffix(name, "_") && strings.HasPrefix(setting, name)) {
+				found.add(setting, "%s", e.Problems[i])
+				break
+			}
+		}
+	}
+	return found
+}
+
+// LoadConfig reads the configuration of the application in rootPath. Values from .env files are
+// loaded into the process environment, so they are also visible to os.Getenv
+func LoadConfig(rootPath string) (*Config, error) {
+	cfg, problems, err := loadConfig(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+
+	return cfg, nil
+}
+
+// LoadConfigFor is LoadConfig for tools that use only some settings, like the ugo command.
+// Only problems with the settings named are reported; a name ending in _ names every setting
+// that starts with it
+func LoadConfigFor(rootPath string, settings ...string) (*Config, error) {
+	cfg, problems, err := loadConfig(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if problems = problems.about(settings); len(problems.Problems) > 0 {
+		return nil, problems
+	}
+
+	return cfg, nil
+}
+
+func loadConfig(rootPath string) (*Config, *ConfigError, error) {
+	fileValues, err := readConfigFile(rootPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = loadDotEnv(rootPath, fileValues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lookup := func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := fileValues[key]
+		return v, ok
+	}
+
+	cfg := &Config{}
+	problems := &ConfigError{}
+	populate(reflect.ValueOf(cfg).Elem(), lookup, problems)
+	cfg.validate(problems)
+
+	return cfg, problems, nil
+}
+
+// loadDotEnv loads .env.<APP_ENV> and then .env. godotenv never overrides a variable that is
+// already set, so the real environment wins over the overlay, and the overlay wins over .env
+func loadDotEnv(rootPath string, fileValues map[string]string) error {
+	base := filepath.Join(rootPath, envFile)
+
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" && fileExists(base) {
+		values, err := godotenv.Read(base)
+		if err != nil {
+			return err
+		}
+		appEnv = values["APP_ENV"]
+	}
+	if appEnv == "" {
+		appEnv = fileValues["APP_ENV"]
+	}
+
+	var files []string
+	if appEnv != "" && fileExists(base+"."+appEnv) {
+		files = append(files, base+"."+appEnv)
+	}
+	if fileExists(base) {
+		files = append(files, base)
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	return godotenv.Load(files...)
+}
+
+// readConfigFile reads config.yaml, config.yml or config.toml from rootPath, flattening nested
+// sections into environment style keys, so database: {host: x} becomes DATABASE_HOST=x
+func readConfigFile(rootPath string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+		path := filepath.Join(rootPath, name)
+		if !fileExists(path) {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var raw map[string]interface{}
+		if strings.HasSuffix(name, ".toml") {
+			err = toml.Unmarshal(data, &raw)
+		} else {
+			err = yaml.Unmarshal(data, &raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		flatten("", raw, values)
+		return values, nil
+	}
+
+	return values, nil
+}
+
+func flatten(prefix string, v interface{}, out map[string]string) {
+	join := func(key interface{}) string {
+		k := strings.ToUpper(fmt.Sprint(key))
+		if prefix == "" {
+			return k
+		}
+		return prefix + "_" + k
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			flatten(join(k), item, out)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			flatten(join(k), item, out)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			items = append(items, fmt.Sprint(item))
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(val)
+	}
+}
+
+// populate walks the fields of v, filling each tagged field and recursing into nested structs
+func populate(v reflect.Value, lookup func(string) (string, bool), problems *ConfigError) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				populate(value, lookup, problems)
+			}
+			continue
+		}
+
+		keys := strings.Split(tag, ",")
+		key := keys[0]
+
+		var raw string
+		for _, k := range keys {
+			if v, found := lookup(k); found && strings.TrimSpace(v) != "" {
+				raw = strings.TrimSpace(v)
+				break
+			}
+		}
+
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				problems.add(key, "%s is required", key)
+				continue
+			}
+			raw = field.Tag.Get("default")
+		}
+
+		if raw != "" && field.Tag.Get("options") != "" {
+			options := strings.Split(field.Tag.Get("options"), ",")
+			if !contains(options, strings.ToLower(raw)) {
+				problems.add(key, "%s must be one of %s; got %q", key, strings.Join(options, ", "), raw)
+				continue
+			}
+			raw = strings.ToLower(raw)
+		}
+
+		if err := setField(value, raw, field.Tag.Get("unit")); err != nil {
+			problems.add(key, "%s is invalid: %s", key, err)
+		}
+	}
+}
+
+func setField(value reflect.Value, raw, unit string) error {
+	if raw == "" {
+		return nil
+	}
+
+	switch value.Interface().(type) {
+	case string:
+		value.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		value.SetBool(b)
+	case int:
+		if unit == "B" {
+			n, err := parseSize(raw)
+			if err != nil {
+				return err
+			}
+			value.SetInt(int64(n))
+			break
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		value.SetInt(int64(n))
+	case time.Duration:
+		d, err := parseDuration(raw, unit)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+
+	return nil
+}
+
+// parseDuration accepts Go durations such as 90s, or plain numbers counted in unit (s, m or h)
+func parseDuration(raw, unit string) (time.Duration, error) {
+	if n, err := strconv.Atoi(raw); err == nil {
+		switch unit {
+		case "m":
+			return time.Duration(n) * time.Minute, nil
+		case "h":
+			return time.Duration(n) * time.Hour, nil
+		default:
+			return time.Duration(n) * time.Second, nil
+		}
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", raw)
+	}
+	return d, nil
+}
+
+// parseSize accepts a number of bytes, or a number followed by KB, MB or GB, counted in 1024s
+func parseSize(raw string) (int, error) {
+	number, multiplier := strings.ToUpper(raw), 1
+	for i, suffix := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(number, suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, suffix)), 1<<(10*(i+1))
+			break
+		}
+	}
+	number = strings.TrimSuffix(number, "B")
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", raw)
+	}
+	return n * multiplier, nil
+}
+
+// validate checks settings that depend on each other
+func (c *Config) validate(problems *ConfigError) {
+	if c.Key != "" && len(c.Key) != 32 {
+		problems.add("KEY", "KEY must be exactly 32 characters long; got %d", len(c.Key))
+	}
+
+	if strings.EqualFold(c.Cookie.SameSite, "none") && !c.Cookie.Secure {
+		problems.add("COOKIE_SECURE", "COOKIE_SECURE must be true when COOKIE_SAME_SITE is none, or browsers reject the cookie")
+	}
+
+	if c.Cookie.IdleTimeout < 0 || c.Cookie.AbsoluteTimeout < 0 {
+		problems.add("SESSION_IDLE_TIMEOUT", "SESSION_IDLE_TIMEOUT and SESSION_ABSOLUTE_TIMEOUT cannot be negative")
+	} else if c.Cookie.IdleTimeout > c.Cookie.sessionLifetime() {
+		problems.add("SESSION_IDLE_TIMEOUT", "SESSION_IDLE_TIMEOUT must not be longer than SESSION_ABSOLUTE_TIMEOUT or COOKIE_LIFETIME")
+	}
+
+	for _, key := range c.PreviousKeys {
+		if len(key) != 32 {
+			problems.add("PREVIOUS_KEYS", "every key in PREVIOUS_KEYS must be exactly 32 characters long; got %d", len(key))
+		}
+	}
+
+	if c.Database.Type == "sqlite" {
+		if c.Database.Name == "" {
+			problems.add("DATABASE_NAME", "DATABASE_NAME is required when DATABASE_TYPE is sqlite")
+		}
+	} else if c.Database.Type != "" {
+		for _, setting := range [][2]string{
+			{"DATABASE_HOST", c.Database.Host},
+			{"DATABASE_USER", c.Database.User},
+			{"DATABASE_NAME", c.Database.Name},
+		} {
+			if setting[1] == "" {
+				problems.add(setting[0], "%s is required when DATABASE_TYPE is %s", setting[0], c.Database.Type)
+			}
+		}
+	}
+
+	if c.CacheMetrics != "" && c.CacheMetricsToken == "" {
+		problems.add("CACHE_METRICS_TOKEN", "CACHE_METRICS_TOKEN is required when CACHE_METRICS_PATH is set")
+	}
+
+	if c.Database.Type == "sqlite" && len(c.Database.Replicas) > 0 {
+		problems.add("DATABASE_REPLICAS", "DATABASE_REPLICAS is not supported when DATABASE_TYPE is sqlite")
+	}
+
+	if len(c.Database.Replicas) > 0 && c.Database.ReplicaCheckInterval <= 0 {
+		problems.add("DATABASE_REPLICA_CHECK_INTERVAL", "DATABASE_REPLICA_CHECK_INTERVAL must be longer than zero when DATABASE_REPLICAS is set")
+	}
+
+	switch c.SessionType {
+	case "mysql", "mariadb", "postgres", "postgresql", "sqlite":
+		if c.Database.Type == "" {
+			problems.add("DATABASE_TYPE", "DATABASE_TYPE is required when SESSION_TYPE is %s", c.SessionType)
+		}
+	}
+
+	if c.Cache == "database" && c.Database.Type == "" {
+		problems.add("DATABASE_TYPE", "DATABASE_TYPE is required when CACHE is database")
+	}
+
+	if (c.Cache == "redis" || c.SessionType == "redis") && c.Redis.Host == "" {
+		problems.add("REDIS_HOST", "REDIS_HOST is required when redis is used for the cache or sessions")
+	}
+
+	if (c.Server.TLSCert == "") != (c.Server.TLSKey == "") {
+		problems.add("TLS_CERT", "TLS_CERT and TLS_KEY must be set together")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,238 @@
+package ugo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+// loadTestConfig writes files to a temporary application folder and loads its configuration
+func loadTestConfig(t *testing.T, files map[string]string) (*Config, error) {
+	return LoadConfig(testApp(t, files))
+}
+
+// testApp writes files to a temporary application folder and returns its path. The
+// variables the .env files put in the environment are removed again when the test ends
+func testApp(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.HasPrefix(name, ".env") {
+			values, err := godotenv.Read(filepath.Join(root, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key := range values {
+				if _, set := os.LookupEnv(key); !set {
+					key := key
+					t.Cleanup(func() { _ = os.Unsetenv(key) })
+				}
+			}
+		}
+	}
+
+	return root
+}
+
+// populateTestConfig fills a Config from env alone, as LoadConfig does
+func populateTestConfig(env map[string]string) (*Config, *ConfigError) {
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	cfg := &Config{}
+	problems := &ConfigError{}
+	populate(reflect.ValueOf(cfg).Elem(), lookup, problems)
+	cfg.validate(problems)
+	return cfg, problems
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	t.Setenv("APP_NAME", "environment")
+
+	cfg, err := loadTestConfig(t, map[string]string{
+		".env":         "APP_ENV=testing\nKEY=" + testKey + "\nAPP_NAME=env\nSERVER_NAME=env\nPORT=5000\n",
+		".env.testing": "APP_NAME=overlay\nSERVER_NAME=overlay\n",
+		"config.yaml":  "app:\n  name: yaml\nserver:\n  name: yaml\nport: 6000\nredis:\n  prefix: yaml\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		setting, got, expected string
+	}{
+		{"APP_NAME", cfg.AppName, "environment"},
+		{"SERVER_NAME", cfg.Server.Name, "overlay"},
+		{"PORT", cfg.Server.Port, "5000"},
+		{"REDIS_PREFIX", cfg.Redis.Prefix, "yaml"},
+		{"DATABASE_TIMEZONE", cfg.Database.Timezone, "UTC"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s: expected %q; got %q", tt.setting, tt.expected, tt.got)
+		}
+	}
+}
+
+func TestLoadConfig_TOML(t *testing.T) {
+	cfg, err := loadTestConfig(t, map[string]string{
+		".env":        "KEY=" + testKey + "\n",
+		"config.toml": "[database]\ntype = \"sqlite\"\nname = \"app.db\"\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Database.Type != "sqlite" || cfg.Database.Name != "app.db" {
+		t.Errorf("expected the database from config.toml; got %+v", cfg.Database)
+	}
+}
+
+func TestConfig_Values(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		got      func(c *Config) interface{}
+		expected interface{}
+	}{
+		{"alias", map[string]string{"COOKIE_PERSISTS": "false"}, func(c *Config) interface{} { return c.Cookie.Persist }, false},
+		{"key_before_alias", map[string]string{"COOKIE_PERSIST": "true", "COOKIE_PERSISTS": "false"}, func(c *Config) interface{} { return c.Cookie.Persist }, true},
+		{"empty_key_uses_alias", map[string]string{"FROM_ADDRESS": " ", "SMTP_FROM": "me@example.com"}, func(c *Config) interface{} { return c.Mail.FromAddress }, "me@example.com"},
+		{"default", nil, func(c *Config) interface{} { return c.Renderer }, "jet"},
+		{"options_any_case", map[string]string{"RENDERER": "Go"}, func(c *Config) interface{} { return c.Renderer }, "go"},
+		{"minutes", map[string]string{"COOKIE_LIFETIME": "90"}, func(c *Config) interface{} { return c.Cookie.Lifetime }, 90 * time.Minute},
+		{"seconds", map[string]string{"SHUTDOWN_TIMEOUT": "45"}, func(c *Config) interface{} { return c.ShutdownTimeout }, 45 * time.Second},
+		{"go_duration", map[string]string{"COOKIE_LIFETIME": "2h"}, func(c *Config) interface{} { return c.Cookie.Lifetime }, 2 * time.Hour},
+		{"default_duration", nil, func(c *Config) interface{} { return c.Database.ConnMaxLifetime }, 5 * time.Minute},
+		{"bytes", map[string]string{"CACHE_MAX_BYTES": "1000"}, func(c *Config) interface{} { return c.CacheMaxBytes }, 1000},
+		{"kilobytes", map[string]string{"CACHE_MAX_BYTES": "512KB"}, func(c *Config) interface{} { return c.CacheMaxBytes }, 512 << 10},
+		{"megabytes", map[string]string{"CACHE_MAX_BYTES": "64mb"}, func(c *Config) interface{} { return c.CacheMaxBytes }, 64 << 20},
+		{"default_size", nil, func(c *Config) interface{} { return c.CacheMaxBytes }, 64 << 20},
+		{"list", map[string]string{"DATABASE_REPLICAS": "a, b,,c"}, func(c *Config) interface{} { return c.Database.Replicas }, []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		env := map[string]string{"KEY": testKey}
+		for k, v := range tt.env {
+			env[k] = v
+		}
+
+		cfg, problems := populateTestConfig(env)
+		if len(problems.Problems) > 0 {
+			t.Errorf("%s: %s", tt.name, problems)
+			continue
+		}
+		if got := tt.got(cfg); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v; got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestConfig_Problems(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		problem string
+	}{
+		{"required", map[string]string{"KEY": ""}, "KEY is required"},
+		{"key_length", map[string]string{"KEY": "short"}, "KEY must be exactly 32 characters long"},
+		{"options", map[string]string{"CACHE": "memcached"}, "CACHE must be one of memory, redis, badger, database"},
+		{"mailer_api", map[string]string{"MAILER_API": "postmark"}, "MAILER_API must be one of"},
+		{"duration", map[string]string{"COOKIE_LIFETIME": "soon"}, "COOKIE_LIFETIME is invalid"},
+		{"size", map[string]string{"CACHE_MAX_BYTES": "lots"}, "CACHE_MAX_BYTES is invalid"},
+		{"boolean", map[string]string{"DEBUG": "maybe"}, "DEBUG is invalid"},
+		{"same_site_none", map[string]string{"COOKIE_SAME_SITE": "none"}, "COOKIE_SECURE must be true"},
+		{"database", map[string]string{"DATABASE_TYPE": "postgres"}, "DATABASE_HOST is required when DATABASE_TYPE is postgres"},
+		{"redis", map[string]string{"SESSION_TYPE": "redis"}, "REDIS_HOST is required"},
+		{"tls", map[string]string{"TLS_CERT": "cert.pem"}, "TLS_CERT and TLS_KEY must be set together"},
+		{"metrics_token", map[string]string{"CACHE_METRICS_PATH": "/metrics"}, "CACHE_METRICS_TOKEN is required"},
+		{"replica_interval", map[string]string{"DATABASE_REPLICAS": "replica", "DATABASE_REPLICA_CHECK_INTERVAL": "0"}, "DATABASE_REPLICA_CHECK_INTERVAL must be longer than zero"},
+	}
+
+	for _, tt := range tests {
+		env := map[string]string{"KEY": testKey}
+		for k, v := range tt.env {
+			env[k] = v
+		}
+
+		_, problems := populateTestConfig(env)
+		if !strings.Contains(problems.Error(), tt.problem) {
+			t.Errorf("%s: expected %q; got %v", tt.name, tt.problem, problems.Problems)
+		}
+	}
+
+	// smtp is accepted, as the mailer sends through SMTP for it
+	if _, problems := populateTestConfig(map[string]string{"KEY": testKey, "MAILER_API": "smtp"}); len(problems.Problems) > 0 {
+		t.Errorf("expected MAILER_API=smtp to be accepted; got %v", problems.Problems)
+	}
+}
+
+func TestLoadConfigFor(t *testing.T) {
+	files := map[string]string{".env": "CACHE=memcached\nDATABASE_TYPE=postgres\n"}
+
+	tests := []struct {
+		settings []string
+		problems []string
+	}{
+		{nil, nil},
+		{[]string{"KEY"}, []string{"KEY is required"}},
+		{[]string{"CACHE"}, []string{"CACHE must be one of"}},
+		{[]string{"DATABASE_"}, []string{"DATABASE_HOST is required", "DATABASE_USER is required", "DATABASE_NAME is required"}},
+	}
+
+	for _, tt := range tests {
+		cfg, err := LoadConfigFor(testApp(t, files), tt.settings...)
+		if len(tt.problems) == 0 {
+			if err != nil || cfg.Database.Type != "postgres" {
+				t.Errorf("%v: expected the configuration; got %v", tt.settings, err)
+			}
+			continue
+		}
+
+		var problems *ConfigError
+		if !errors.As(err, &problems) || len(problems.Problems) != len(tt.problems) {
+			t.Errorf("%v: expected %d problems; got %v", tt.settings, len(tt.problems), err)
+			continue
+		}
+		for i, problem := range tt.problems {
+			if !strings.Contains(problems.Problems[i], problem) {
+				t.Errorf("%v: expected %q; got %q", tt.settings, problem, problems.Problems[i])
+			}
+		}
+	}
+}
+
+func TestLoadConfig_AllProblems(t *testing.T) {
+	_, err := loadTestConfig(t, map[string]string{
+		".env": "CACHE=memcached\nCOOKIE_LIFETIME=soon\nTLS_KEY=key.pem\n",
+	})
+
+	var problems *ConfigError
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected a ConfigError; got %v", err)
+	}
+
+	expected := []string{"KEY is required", "CACHE must be one of", "COOKIE_LIFETIME is invalid", "TLS_CERT and TLS_KEY must be set together"}
+	if len(problems.Problems) != len(expected) {
+		t.Errorf("expected %d problems; got %v", len(expected), problems.Problems)
+	}
+	for _, problem := range expected {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected the error to list %q; got %s", problem, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ugo
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Writer returns the pool for the primary database. Use it for every write, and for reads
+// that must see the result of a write straight away
+func (d *database) Writer() *sql.DB {
+	return d.Pool
+}
+
+// Reader returns a pool for read-only queries. Healthy replicas are used in turn, and the
+// primary is returned when there are no replicas or none of them are healthy
+func (d *database) Reader() *sql.DB {
+	n := len(d.replicas)
+	if n == 0 {
+		return d.Pool
+	}
+
+	start := atomic.AddUint32(&d.next, 1)
+	for i := 0; i < n; i++ {
+		r := d.replicas[(int(start)+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.pool
+		}
+	}
+
+	return d.Pool
+}
+
+// Close stops the replica health checks and closes the primary and replica pools
+func (d *database) Close() error {
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+	}
+
+	for _, r := range d.replicas {
+		_ = r.pool.Close()
+	}
+
+	return d.Pool.Close()
+}
+
+// checkReplicas pings every replica and marks it healthy or not
+func (d *database) checkReplicas(timeout time.Duration) {
+	for _, r := range d.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := r.pool.PingContext(ctx); err != nil {
+			atomic.StoreInt32(&r.healthy, 0)
+		} else {
+			atomic.StoreInt32(&r.healthy, 1)
+		}
+		cancel()
+	}
+}
+
+func (d *database) watchReplicas(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.checkReplicas(interval)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// configurePool applies the configured connection pool limits to db
+func (u *Ugo) configurePool(db *sql.DB) {
+	db.SetMaxOpenConns(u.Config.Database.MaxOpenConns)
+	db.SetMaxIdleConns(u.Config.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(u.Config.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(u.Config.Database.ConnMaxIdleTime)
+}
+
+// openReplicas connects to every configured read replica and starts checking their health.
+// A replica that cannot be reached at start up is kept, but not used until it answers a ping
+func (u *Ugo) openReplicas() error {
+	if len(u.Config.Database.Replicas) == 0 {
+		return nil
+	}
+
+	for _, addr := range u.Config.Database.Replicas {
+		cfg := u.Config.Database
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, cfg.Port
+		}
+		cfg.Host, cfg.Port = host, port
+
+		pool, err := sql.Open(driverName(cfg.Type), u.buildDSN(cfg))
+		if err != nil {
+			return err
+		}
+		u.configurePool(pool)
+
+		u.DB.replicas = append(u.DB.replicas, &replica{pool: pool})
+	}
+
+	u.DB.checkReplicas(5 * time.Second)
+	for i, r := range u.DB.replicas {
+		if atomic.LoadInt32(&r.healthy) == 0 {
+			u.ErrorLog.Printf("database replica %s is not reachable\n", u.Config.Database.Replicas[i])
+		}
+	}
+
+	u.DB.stop = make(chan struct{})
+	go u.DB.watchReplicas(u.Config.Database.ReplicaCheckInterval, u.DB.stop)
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package ugo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConnector connects to nothing, and fails to connect or ping while down is set
+type testConnector struct {
+	down int32
+}
+
+func (c *testConnector) Connect(context.Context) (driver.Conn, error) {
+	if atomic.LoadInt32(&c.down) == 1 {
+		return nil, errors.New("connection refused")
+	}
+	return &testConn{connector: c}, nil
+}
+
+func (c *testConnector) Driver() driver.Driver {
+	return nil
+}
+
+type testConn struct {
+	connector *testConnector
+}
+
+func (c *testConn) Ping(context.Context) error {
+	if atomic.LoadInt32(&c.connector.down) == 1 {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func testReplica(c *testConnector) *replica {
+	return &replica{pool: sql.OpenDB(c)}
+}
+
+func TestDatabase_Reader(t *testing.T) {
+	primary := sql.OpenDB(&testConnector{})
+	first, second := &testConnector{}, &testConnector{}
+
+	d := &database{Pool: primary, replicas: []*replica{testReplica(first), testReplica(second)}}
+	defer d.Close()
+
+	if d.Reader() != primary {
+		t.Error("expected the primary before the replicas are checked")
+	}
+
+	d.checkReplicas(time.Second)
+	seen := map[*sql.DB]bool{}
+	for i := 0; i < 4; i++ {
+		seen[d.Reader()] = true
+	}
+	if len(seen) != 2 || seen[primary] {
+		t.Errorf("expected reads to be shared by both replicas; got %d pools", len(seen))
+	}
+
+	// a replica that stops answering is skipped
+	atomic.StoreInt32(&first.down, 1)
+	d.checkReplicas(time.Second)
+	for i := 0; i < 4; i++ {
+		if d.Reader() != d.replicas[1].pool {
+			t.Fatal("expected reads to fail over to the healthy replica")
+		}
+	}
+
+	// without a healthy replica, reads go to the primary
+	atomic.StoreInt32(&second.down, 1)
+	d.checkReplicas(time.Second)
+	if d.Reader() != primary {
+		t.Error("expected reads to fall back to the primary")
+	}
+
+	// a replica that answers again is used again
+	atomic.StoreInt32(&first.down, 0)
+	d.checkReplicas(time.Second)
+	if d.Reader() != d.replicas[0].pool {
+		t.Error("expected reads to return to the recovered replica")
+	}
+}
+
+func TestDatabase_WatchReplicas(t *testing.T) {
+	c := &testConnector{down: 1}
+	d := &database{Pool: sql.OpenDB(&testConnector{}), replicas: []*replica{testReplica(c)}, stop: make(chan struct{})}
+	defer d.Close()
+
+	go d.watchReplicas(10*time.Millisecond, d.stop)
+
+	atomic.StoreInt32(&c.down, 0)
+	deadline := time.Now().Add(time.Second)
+	for d.Reader() != d.replicas[0].pool {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the replica to be used once it answers a ping")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDatabase_Writer(t *testing.T) {
+	primary := sql.OpenDB(&testConnector{})
+	d := &database{Pool: primary, replicas: []*replica{testReplica(&testConnector{})}}
+	defer d.Close()
+
+	d.checkReplicas(time.Second)
+	if d.Writer() != primary {
+		t.Error("expected writes to go to the primary")
+	}
+}
@@ -3,18 +3,16 @@ package ugo
 import (
 	"database/sql"
 
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4"
 	_ "github.com/jackc/pgx/v4/stdlib"
+	_ "modernc.org/sqlite"
 )
 
 func (U *Ugo) OpenDB(dbType, dsn string) (*sql.DB, error) {
 
-	if dbType == "postgres" || dbType == "postgressql" {
-		dbType = "pgx"
-	}
-
-	db, err := sql.Open(dbType, dsn)
+	db, err := sql.Open(driverName(dbType), dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -27,3 +25,15 @@ func (U *Ugo) OpenDB(dbType, dsn string) (*sql.DB, error) {
 	return db, nil
 
 }
+
+// driverName returns the database/sql driver registered for a DATABASE_TYPE
+func driverName(dbType string) string {
+	switch dbType {
+	case "postgres", "postgresql", "postgressql":
+		return "pgx"
+	case "mysql", "mariadb":
+		return "mysql"
+	default:
+		return dbType
+	}
+}
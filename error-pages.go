@@ -0,0 +1,284 @@
+package ugo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joefazee/ugo/render"
+)
+
+// Problem is an RFC 7807 problem response, sent instead of an error page to API clients
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// ErrorPage replies with the error page for status. API requests get a JSON problem.
+// Browsers get views/errors/<status>, such as views/errors/404.page.jet, when it exists,
+// and plain text otherwise. In debug mode a server error shows err, the stack trace and
+// the request instead. err may be nil; it is never shown outside debug mode
+func (u *Ugo) ErrorPage(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var stack []byte
+	if u.Debug {
+		stack = debug.Stack()
+	}
+	u.errorPage(w, r, status, err, stack)
+}
+
+func (u *Ugo) errorPage(w http.ResponseWriter, r *http.Request, status int, err error, stack []byte) {
+	if err != nil && status >= http.StatusInternalServerError {
+		u.ErrorLog.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+	}
+
+	if isAPIRequest(r) {
+		u.writeProblem(w, r, status, err)
+		return
+	}
+
+	if u.Debug && status >= http.StatusInternalServerError {
+		u.writeDebugPage(w, r, status, err, stack)
+		return
+	}
+
+	view := "errors/" + strconv.Itoa(status)
+	if u.Render != nil && u.Render.HasView(view) {
+		td := &render.TemplateData{Data: map[string]interface{}{
+			"status":  status,
+			"title":   http.StatusText(status),
+			"path":    r.URL.Path,
+			"request": r.Header.Get("X-Request-Id"),
+		}}
+
+		// rendered aside, so a broken template still gets a plain error
+		buf := &bufferedWriter{header: w.Header()}
+		renderErr := u.Render.Page(buf, r, view, nil, td)
+		if renderErr == nil {
+			if w.Header().Get("Content-Type") == "" {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write(buf.body.Bytes())
+			return
+		}
+		u.ErrorLog.Println("error rendering error page:", renderErr)
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// isAPIRequest reports whether r came from an API client rather than a browser: its path is
+// under /api/, or it accepts JSON but not HTML
+func isAPIRequest(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "json") && !strings.Contains(accept, "text/html")
+}
+
+func (u *Ugo) writeProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+	}
+	if u.Debug && err != nil {
+		problem.Detail = err.Error()
+	}
+
+	out, _ := json.MarshalIndent(problem, "", "\t")
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write(out)
+}
+
+var debugPage = template.Must(template.New("debug").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+h1 { color: #b00020; }
+pre { background: #f5f5f5; padding: 1rem; overflow-x: auto; font-size: 0.85rem; }
+th { text-align: left; padding-right: 1rem; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Error}}<pre>{{.Error}}</pre>{{end}}
+<h2>Request</h2>
+<table>
+<tr><th>Method</th><td>{{.Method}}</td></tr>
+<tr><th>URL</th><td>{{.URL}}</td></tr>
+<tr><th>Remote address</th><td>{{.RemoteAddr}}</td></tr>
+{{range .Headers}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Stack trace</h2>
+<pre>{{.Stack}}</pre>
+<p><small>This page is only shown in debug mode.</small></p>
+</body>
+</html>
+`))
+
+type debugHeader struct {
+	Name, Value string
+}
+
+func (u *Ugo) writeDebugPage(w http.ResponseWriter, r *http.Request, status int, err error, stack []byte) {
+	data := struct {
+		Status                  int
+		Title, Error, Stack     string
+		Method, URL, RemoteAddr string
+		Headers                 []debugHeader
+	}{
+		Status:     status,
+		Title:      http.StatusText(status),
+		Stack:      string(stack),
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		RemoteAddr: r.RemoteAddr,
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	for name, values := range r.Header {
+		// credentials stay out of the page, even in debug mode
+		if name == "Cookie" || name == "Authorization" {
+			values = []string{"[hidden]"}
+		}
+		data.Headers = append(data.Headers, debugHeader{name, strings.Join(values, ", ")})
+	}
+	sort.Slice(data.Headers, func(i, j int) bool { return data.Headers[i].Name < data.Headers[j].Name })
+
+	var buf bytes.Buffer
+	if err := debugPage.Execute(&buf, data); err != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// Recoverer is middleware that turns a panic into a 500 error page, logging the panic and
+// its stack trace. In debug mode the page shows them
+func (u *Ugo) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+
+			// the server aborts the response on purpose; leave it to net/http
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			stack := debug.Stack()
+			u.ErrorLog.Printf("panic: %v\n%s", rvr, stack)
+
+			// the panic unwound the session middleware, so load an empty session for the page
+			if u.Session != nil {
+				if ctx, err := u.Session.Load(r.Context(), ""); err == nil {
+					r = r.WithContext(ctx)
+				}
+			}
+
+			u.errorPage(w, r, http.StatusInternalServerError, fmt.Errorf("panic: %v", rvr), stack)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NotFound replies with the 404 error page
+func (u *Ugo) NotFound(w http.ResponseWriter, r *http.Request) {
+	u.ErrorPage(w, r, http.StatusNotFound, nil)
+}
+
+// MethodNotAllowed replies with the 405 error page
+func (u *Ugo) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	u.ErrorPage(w, r, http.StatusMethodNotAllowed, nil)
+}
+
+// keepRequest is middleware that lets the error helpers that only take a ResponseWriter,
+// such as Error404 and ErrorStatus, find the request and render the error page for it
+func (u *Ugo) keepRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&requestWriter{ResponseWriter: w, request: r}, r)
+	})
+}
+
+// requestWriter carries the request being served alongside its ResponseWriter
+type requestWriter struct {
+	http.ResponseWriter
+	request *http.Request
+}
+
+func (rw *requestWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *requestWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *requestWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the response writer does not support hijacking")
+}
+
+// requestOf returns the request w is serving, when keepRequest has run
+func requestOf(w http.ResponseWriter) *http.Request {
+	for {
+		switch rw := w.(type) {
+		case *requestWriter:
+			return rw.request
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// bufferedWriter collects a response, so it can be discarded if rendering fails halfway
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedWriter) WriteHeader(int) {}
@@ -0,0 +1,248 @@
+package ugo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/joefazee/ugo/i18n"
+	"github.com/joefazee/ugo/render"
+	"github.com/joefazee/ugo/session"
+)
+
+// errorPageApp returns the routes of an application with a 404 page and a broken 500 page,
+// and handlers that fail in every way the error pages handle
+func errorPageApp(t *testing.T, debug bool) http.Handler {
+	sm := scs.New()
+	bundle, err := i18n.Load(fstest.MapFS{}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Ugo{
+		Debug:    debug,
+		ErrorLog: log.New(io.Discard, "", 0),
+		Session:  sm,
+		Sessions: session.NewRegistry(sm, []byte(testKey)),
+		I18n:     bundle,
+		Render: &render.Render{Renderer: "go", Views: fstest.MapFS{
+			"errors/404.page.html": {Data: []byte(`<h1>{{index .Data "title"}}</h1><p>{{index .Data "path"}}</p>`)},
+			"errors/500.page.html": {Data: []byte(`{{template "missing" .}}`)},
+		}},
+	}
+	u.Config.Locale.Cookie = "lang"
+
+	mux := u.routes().(*chi.Mux)
+	mux.Post("/items", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		u.ErrorPage(w, r, http.StatusInternalServerError, errors.New("database is down"))
+	})
+	mux.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.Get("/gone", func(w http.ResponseWriter, r *http.Request) {
+		u.Error404(w)
+	})
+
+	return mux
+}
+
+func errorPageRequest(h http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Cookie", "secret=1")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestErrorPage_HTML(t *testing.T) {
+	h := errorPageApp(t, false)
+
+	tests := []struct {
+		name, path  string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"not_found_template", "/missing", http.StatusNotFound, "text/html", "<h1>Not Found</h1><p>/missing</p>"},
+		{"not_found_from_handler", "/gone", http.StatusNotFound, "text/html", "<h1>Not Found</h1><p>/gone</p>"},
+		{"method_not_allowed_plain", "/items", http.StatusMethodNotAllowed, "text/plain", "Method Not Allowed"},
+		{"server_error_broken_template", "/fail", http.StatusInternalServerError, "text/plain", "Internal Server Error"},
+		{"panic", "/panic", http.StatusInternalServerError, "text/plain", "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		rr := errorPageRequest(h, tt.path, "text/html,application/xhtml+xml")
+
+		if rr.Code != tt.status {
+			t.Errorf("%s: expected status %d; got %d", tt.name, tt.status, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("%s: expected %s; got %s", tt.name, tt.contentType, ct)
+		}
+		if !strings.Contains(rr.Body.String(), tt.body) {
+			t.Errorf("%s: expected %q in %q", tt.name, tt.body, rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), "database is down") || strings.Contains(rr.Body.String(), "boom") {
+			t.Errorf("%s: the error must not be shown outside debug mode", tt.name)
+		}
+	}
+}
+
+func TestErrorPage_Problem(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		h := errorPageApp(t, debug)
+
+		tests := []struct {
+			name, path, accept string
+			status             int
+			detail             string
+		}{
+			{"not_found", "/missing", "application/json", http.StatusNotFound, ""},
+			{"not_found_api_path", "/api/missing", "", http.StatusNotFound, ""},
+			{"not_found_from_handler", "/gone", "application/json", http.StatusNotFound, ""},
+			{"method_not_allowed", "/items", "application/json", http.StatusMethodNotAllowed, ""},
+			{"server_error", "/fail", "application/json", http.StatusInternalServerError, "database is down"},
+			{"panic", "/panic", "application/json", http.StatusInternalServerError, "panic: boom"},
+		}
+
+		for _, tt := range tests {
+			rr := errorPageRequest(h, tt.path, tt.accept)
+
+			if rr.Code != tt.status {
+				t.Errorf("%s: expected status %d; got %d", tt.name, tt.status, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s: expected application/problem+json; got %s", tt.name, ct)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+				continue
+			}
+			if problem.Status != tt.status || problem.Title != http.StatusText(tt.status) || problem.Instance != tt.path {
+				t.Errorf("%s: unexpected problem %+v", tt.name, problem)
+			}
+
+			// the cause of the error is only given in debug mode
+			detail := ""
+			if debug {
+				detail = tt.detail
+			}
+			if problem.Detail != detail {
+				t.Errorf("%s: expected detail %q in debug mode %v; got %q", tt.name, detail, debug, problem.Detail)
+			}
+		}
+	}
+}
+
+func TestErrorPage_Debug(t *testing.T) {
+	h := errorPageApp(t, true)
+
+	tests := []struct {
+		name, path string
+		status     int
+		body       []string
+	}{
+		{"server_error", "/fail", http.StatusInternalServerError, []string{"database is down", "Stack trace", "/fail"}},
+		{"panic", "/panic", http.StatusInternalServerError, []string{"panic: boom", "Stack trace"}},
+		// client errors keep their page in debug mode
+		{"not_found", "/missing", http.StatusNotFound, []string{"<h1>Not Found</h1>"}},
+	}
+
+	for _, tt := range tests {
+		rr := errorPageRequest(h, tt.path, "text/html")
+
+		if rr.Code != tt.status {
+			t.Errorf("%s: expected status %d; got %d", tt.name, tt.status, rr.Code)
+		}
+		for _, s := range tt.body {
+			if !strings.Contains(rr.Body.String(), s) {
+				t.Errorf("%s: expected %q in the page", tt.name, s)
+			}
+		}
+		if strings.Contains(rr.Body.String(), "secret=1") {
+			t.Errorf("%s: expected the cookie to be hidden", tt.name)
+		}
+	}
+}
+
+func TestRecoverer_AbortHandler(t *testing.T) {
+	u := &Ugo{ErrorLog: log.New(io.Discard, "", 0)}
+	h := u.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rvr := recover(); rvr != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to reach net/http; got %v", rvr)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoverer_BeforeLocale(t *testing.T) {
+	// without a bundle, Locale panics, and the panic must still become an error page
+	u := &Ugo{ErrorLog: log.New(io.Discard, "", 0), Session: scs.New()}
+	h := u.routes().(*chi.Mux)
+	h.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	rr := errorPageRequest(h, "/", "application/json")
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected a panic in Locale to be recovered; got %d", rr.Code)
+	}
+}
+
+// unwrappingWriter wraps a ResponseWriter the way middleware such as chi's does
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestKeepRequest(t *testing.T) {
+	u := &Ugo{}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+
+	u.keepRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestOf(w) != r {
+			t.Error("expected the request to be found from the writer")
+		}
+
+		// the request is still found when other middleware wraps the writer again
+		if requestOf(unwrappingWriter{w}) != r {
+			t.Error("expected the request to be found through a wrapped writer")
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected the writer to flush")
+		}
+		f.Flush()
+
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Error("expected an error hijacking a writer that cannot be hijacked")
+		}
+	})).ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("expected the flush to reach the underlying writer")
+	}
+
+	if requestOf(rr) != nil {
+		t.Error("expected no request outside keepRequest")
+	}
+}
@@ -0,0 +1,242 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Bundle holds the translation catalogs of every language, read once by Load
+type Bundle struct {
+	// Default is the language used when the client asks for none that has a catalog
+	Default string
+
+	catalogs    map[string]map[string]string
+	translators map[string]*Translator
+}
+
+// Load reads the catalogs in fsys. A catalog is named after its language, such as fr.json or
+// pt-BR.toml, or sits in a folder named after it, such as fr/validation.json. Nested objects
+// are flattened into dotted keys, so {"auth": {"login": "Log in"}} is the key auth.login
+func Load(fsys fs.FS, defaultLang string) (*Bundle, error) {
+	b := &Bundle{
+		Default:  Normalize(defaultLang),
+		catalogs: make(map[string]map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		// without a folder of catalogs, every message is its key
+		if name == "." && errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+
+		ext := path.Ext(name)
+		if d.IsDir() || (ext != ".json" && ext != ".toml") {
+			return nil
+		}
+
+		// the folder names the language when there is one, and the file otherwise
+		lang, _, ok := strings.Cut(name, "/")
+		if !ok {
+			lang = strings.TrimSuffix(name, ext)
+		}
+		lang = Normalize(lang)
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		var raw map[string]interface{}
+		if ext == ".toml" {
+			err = toml.Unmarshal(data, &raw)
+		} else {
+			err = json.Unmarshal(data, &raw)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if b.catalogs[lang] == nil {
+			b.catalogs[lang] = make(map[string]string)
+		}
+		return flatten(b.catalogs[lang], "", raw, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.translators = make(map[string]*Translator)
+	for lang := range b.catalogs {
+		b.translators[lang] = b.newTranslator(lang)
+	}
+	if b.translators[b.Default] == nil {
+		b.translators[b.Default] = b.newTranslator(b.Default)
+	}
+
+	return b, nil
+}
+
+func flatten(catalog map[string]string, prefix string, raw map[string]interface{}, file string) error {
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			catalog[prefix+key] = v
+		case map[string]interface{}:
+			if err := flatten(catalog, prefix+key+".", v, file); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: %s%s must be a string or an object", file, prefix, key)
+		}
+	}
+	return nil
+}
+
+// newTranslator looks messages up in lang, then in its base language, then in the default
+func (b *Bundle) newTranslator(lang string) *Translator {
+	t := &Translator{Lang: lang}
+
+	seen := make(map[string]bool)
+	for _, l := range []string{lang, baseLanguage(lang), b.Default} {
+		if catalog, ok := b.catalogs[l]; ok && !seen[l] {
+			t.catalogs = append(t.catalogs, catalog)
+			seen[l] = true
+		}
+	}
+
+	return t
+}
+
+// Languages lists the languages that have a catalog, in alphabetical order
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Supports reports whether lang has a catalog of its own
+func (b *Bundle) Supports(lang string) bool {
+	_, ok := b.catalogs[Normalize(lang)]
+	return ok
+}
+
+// Translator returns the translator for lang. A regional language without a catalog, such as
+// fr-CA, falls back to its base language, and any other language to the default
+func (b *Bundle) Translator(lang string) *Translator {
+	lang = Normalize(lang)
+	if t, ok := b.translators[lang]; ok {
+		return t
+	}
+	if t, ok := b.translators[baseLanguage(lang)]; ok {
+		return t
+	}
+	return b.translators[b.Default]
+}
+
+// Match returns the language with a catalog the client prefers, according to an
+// Accept-Language header, or false when it accepts none of them
+func (b *Bundle) Match(acceptLanguage string) (string, bool) {
+	for _, lang := range parseAcceptLanguage(acceptLanguage) {
+		if lang == "*" {
+			return b.Default, true
+		}
+		if b.Supports(lang) {
+			return lang, true
+		}
+		if base := baseLanguage(lang); b.Supports(base) {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// parseAcceptLanguage lists the languages of an Accept-Language header, most preferred first,
+// leaving out those with a quality of zero
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" {
+			continue
+		}
+
+		t := tag{lang: Normalize(lang), q: 1}
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(name) == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+
+	// stable, so languages of equal quality keep the order the client gave
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	langs := make([]string, len(tags))
+	for i, t := range tags {
+		langs[i] = t.lang
+	}
+	return langs
+}
+
+// Normalize writes a language tag the way catalogs are named: pt_br and PT-br become pt-BR
+func Normalize(lang string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func baseLanguage(lang string) string {
+	base, _, _ := strings.Cut(lang, "-")
+	return base
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t *Translator) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the translator of ctx, or nil when there is none. A nil translator
+// still works: it returns the keys it is given
+func FromContext(ctx context.Context) *Translator {
+	t, _ := ctx.Value(contextKey{}).(*Translator)
+	return t
+}
@@ -0,0 +1,165 @@
+package i18n
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func loadTestBundle(t *testing.T) *Bundle {
+	b, err := Load(os.DirFS("./testdata/lang"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBundle_Load(t *testing.T) {
+
+	b := loadTestBundle(t)
+
+	if got := b.Languages(); !reflect.DeepEqual(got, []string{"en", "fr", "ru"}) {
+		t.Errorf("expected en, fr and ru; got %v", got)
+	}
+
+	// fr.toml and fr/validation.json are one catalog
+	fr := b.Translator("fr")
+	if got := fr.T("auth.login"); got != "Se connecter" {
+		t.Errorf("expected the toml message; got %q", got)
+	}
+	if got := fr.T("validation.required"); got != "ce champ est obligatoire" {
+		t.Errorf("expected the message from the fr folder; got %q", got)
+	}
+
+	_, err := Load(fstest.MapFS{"en.json": {Data: []byte(`{"count": 3}`)}}, "en")
+	if err == nil {
+		t.Error("expected an error for a message that is not a string")
+	}
+}
+
+func TestTranslator_T(t *testing.T) {
+
+	b := loadTestBundle(t)
+
+	tests := []struct {
+		name, lang, key string
+		args            []interface{}
+		expected        string
+	}{
+		{"message", "en", "auth.login", nil, "Log in"},
+		{"replace", "fr", "welcome", []interface{}{"name", "Ada"}, "Bienvenue, Ada"},
+		{"fallback_to_default", "fr", "auth.logout", nil, "Log out"},
+		{"regional_to_base", "fr-CA", "auth.login", nil, "Se connecter"},
+		{"unknown_language", "de", "auth.login", nil, "Log in"},
+		{"missing_key", "en", "nope.{name}", []interface{}{"name", "x"}, "nope.x"},
+		{"zero", "en", "inbox", []interface{}{"count", 0}, "No messages"},
+		{"one", "en", "inbox", []interface{}{"count", 1}, "One message"},
+		{"other", "en", "inbox", []interface{}{"count", 5}, "5 messages"},
+		{"float_count", "en", "inbox", []interface{}{"count", 2.0}, "2 messages"},
+		{"fraction", "en", "inbox", []interface{}{"count", 1.5}, "1.5 messages"},
+		{"french_zero", "fr", "inbox", []interface{}{"count", 0}, "0 message"},
+		{"russian_one", "ru", "inbox", []interface{}{"count", 21}, "21 сообщение"},
+		{"russian_few", "ru", "inbox", []interface{}{"count", 3}, "3 сообщения"},
+		{"russian_many", "ru", "inbox", []interface{}{"count", 11}, "11 сообщений"},
+	}
+
+	for _, tt := range tests {
+		if got := b.Translator(tt.lang).T(tt.key, tt.args...); got != tt.expected {
+			t.Errorf("%s: expected %q; got %q", tt.name, tt.expected, got)
+		}
+	}
+
+	var nilTranslator *Translator
+	if got := nilTranslator.T("auth.login"); got != "auth.login" {
+		t.Errorf("expected a nil translator to return the key; got %q", got)
+	}
+}
+
+func TestBundle_Match(t *testing.T) {
+
+	b := loadTestBundle(t)
+
+	tests := []struct {
+		header, lang string
+		ok           bool
+	}{
+		{"fr-FR,fr;q=0.9,en;q=0.8", "fr", true},
+		{"de, ru;q=0.5, en;q=0.7", "en", true},
+		{"RU", "ru", true},
+		{"fr;q=0, en", "en", true},
+		{"de, *;q=0.1", "en", true},
+		{"de", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		lang, ok := b.Match(tt.header)
+		if lang != tt.lang || ok != tt.ok {
+			t.Errorf("%q: expected %q, %v; got %q, %v", tt.header, tt.lang, tt.ok, lang, ok)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+
+	tests := []struct {
+		lang     string
+		n        int
+		expected string
+	}{
+		{"en", 1, "one"},
+		{"en", 0, "other"},
+		{"fr", 0, "one"},
+		{"pt-BR", 1, "one"},
+		{"pt-PT", 0, "other"},
+		{"ja", 1, "other"},
+		{"pl", 22, "few"},
+		{"pl", 12, "many"},
+		{"cs", 3, "few"},
+		{"ar", 2, "two"},
+		{"ar", 105, "few"},
+		{"ar", 111, "many"},
+	}
+
+	for _, tt := range tests {
+		if got := Plural(tt.lang, tt.n); got != tt.expected {
+			t.Errorf("%s %d: expected %s; got %s", tt.lang, tt.n, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+
+	for in, expected := range map[string]string{"pt_br": "pt-BR", "EN": "en", "zh-hant-tw": "zh-Hant-TW"} {
+		if got := Normalize(in); got != expected {
+			t.Errorf("%s: expected %s; got %s", in, expected, got)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+
+	b := loadTestBundle(t)
+
+	if FromContext(context.Background()) != nil {
+		t.Error("expected no translator")
+	}
+
+	ctx := NewContext(context.Background(), b.Translator("fr"))
+	if tr := FromContext(ctx); tr == nil || tr.Lang != "fr" {
+		t.Errorf("expected the fr translator; got %v", tr)
+	}
+}
+
+func TestLoad_NoFolder(t *testing.T) {
+
+	b, err := Load(os.DirFS("./testdata/missing"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Translator("fr").T("auth.login"); got != "auth.login" {
+		t.Errorf("expected the key; got %q", got)
+	}
+}
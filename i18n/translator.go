@@ -0,0 +1,194 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Translator translates messages into one language
+type Translator struct {
+	// Lang is the language the translator was asked for, such as fr or pt-BR
+	Lang string
+
+	// the catalogs messages are looked up in, most specific first
+	catalogs []map[string]string
+}
+
+// T translates key. args are name and value pairs replacing {name} in the message, so
+// T("welcome", "name", "Ada") turns "Welcome, {name}" into "Welcome, Ada". A count argument
+// picks the plural form: T("inbox", "count", 3) reads inbox.one, inbox.few, inbox.other and
+// so on, as the language's plural rules require. When there is no message, T returns key
+func (t *Translator) T(key string, args ...interface{}) string {
+	msg, ok := t.Lookup(key, args...)
+	if !ok {
+		return replace(key, args)
+	}
+	return msg
+}
+
+// Lookup is T, reporting whether key has a message instead of falling back to the key
+func (t *Translator) Lookup(key string, args ...interface{}) (string, bool) {
+	var keys []string
+	if count, ok := argument(args, "count"); ok {
+		n, whole := toInt(count)
+		// zero may always have a message of its own, whatever the rules of the language
+		if whole && n == 0 {
+			keys = append(keys, key+".zero")
+		}
+		if whole {
+			keys = append(keys, key+"."+Plural(t.lang(), n))
+		}
+		keys = append(keys, key+".other")
+	}
+	keys = append(keys, key)
+
+	// a language's own plural forms come before any form of the languages it falls back to
+	if t != nil {
+		for _, catalog := range t.catalogs {
+			for _, k := range keys {
+				if msg, ok := catalog[k]; ok {
+					return replace(msg, args), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func (t *Translator) lang() string {
+	if t == nil {
+		return ""
+	}
+	return t.Lang
+}
+
+// argument returns the value of the name and value pair called name
+func argument(args []interface{}, name string) (interface{}, bool) {
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == name {
+			return args[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func replace(msg string, args []interface{}) string {
+	if len(args) < 2 || !strings.Contains(msg, "{") {
+		return msg
+	}
+
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("{%v}", args[i]), fmt.Sprint(args[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// toInt converts a count to an int. Jet passes numbers as floats, so whole floats count too
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float32:
+		return int(n), float32(int(n)) == n
+	case float64:
+		return int(n), float64(int(n)) == n
+	}
+	return 0, false
+}
+
+// Plural returns the plural category of n in lang: zero, one, two, few, many or other, as
+// defined by the Unicode CLDR for whole numbers. Languages without rules of their own use
+// the English ones
+func Plural(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+
+	switch baseLanguage(Normalize(lang)) {
+	case "ja", "zh", "ko", "vi", "th", "id", "ms", "tr", "ka":
+		return "other"
+
+	case "fr", "hi", "bn", "fa", "am", "zu":
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+
+	case "pt":
+		// European Portuguese follows English, Brazilian Portuguese follows French
+		if Normalize(lang) == "pt-PT" {
+			break
+		}
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+
+	case "ru", "uk", "be", "sr", "hr", "bs":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		}
+		return "many"
+
+	case "pl":
+		switch {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		}
+		return "many"
+
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		}
+		return "other"
+
+	case "ar":
+		switch {
+		case n == 0:
+			return "zero"
+		case n == 1:
+			return "one"
+		case n == 2:
+			return "two"
+		case mod100 >= 3 && mod100 <= 10:
+			return "few"
+		case mod100 >= 11:
+			return "many"
+		}
+		return "other"
+	}
+
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
@@ -0,0 +1,85 @@
+package ugo
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/joefazee/ugo/i18n"
+)
+
+// Locale is middleware that picks the language of a request from the first of: a language
+// prefix on the path, such as /fr/about, the language cookie set by SetLocale, and the
+// Accept-Language header, falling back to LOCALE. The prefix is removed before routing, so
+// routes are declared once for every language
+func (u *Ugo) Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lang string
+
+		if u.Config.Locale.URLPrefix {
+			prefix, rest := splitLocalePrefix(r.URL.Path)
+			if prefix != "" && u.I18n.Supports(prefix) {
+				lang = prefix
+
+				// the URL is shared with the caller's request, so it is copied before changing it
+				url := *r.URL
+				url.Path = rest
+				url.RawPath = ""
+				r = r.Clone(r.Context())
+				r.URL = &url
+			}
+		}
+
+		if lang == "" {
+			if cookie, err := r.Cookie(u.Config.Locale.Cookie); err == nil && u.I18n.Supports(cookie.Value) {
+				lang = cookie.Value
+			}
+		}
+
+		if lang == "" {
+			w.Header().Add("Vary", "Accept-Language")
+			lang, _ = u.I18n.Match(r.Header.Get("Accept-Language"))
+		}
+
+		tr := u.I18n.Translator(lang)
+		w.Header().Set("Content-Language", tr.Lang)
+
+		next.ServeHTTP(w, r.WithContext(i18n.NewContext(r.Context(), tr)))
+	})
+}
+
+// splitLocalePrefix splits /fr/about into fr and /about
+func splitLocalePrefix(path string) (string, string) {
+	prefix, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return prefix, "/" + rest
+}
+
+// SetLocale remembers lang as the language of the client, for a language switcher
+func (u *Ugo) SetLocale(w http.ResponseWriter, lang string) error {
+	if !u.I18n.Supports(lang) {
+		return fmt.Errorf("there is no catalog for %q", lang)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     u.Config.Locale.Cookie,
+		Value:    i18n.Normalize(lang),
+		Path:     "/",
+		Domain:   u.Config.Cookie.Domain,
+		Expires:  time.Now().AddDate(1, 0, 0),
+		HttpOnly: true,
+		Secure:   u.Config.Cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+// Translator returns the translator for the language of r, or for LOCALE when the Locale
+// middleware has not run
+func (u *Ugo) Translator(r *http.Request) *i18n.Translator {
+	if tr := i18n.FromContext(r.Context()); tr != nil {
+		return tr
+	}
+	return u.I18n.Translator(u.I18n.Default)
+}
@@ -0,0 +1,151 @@
+package ugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/joefazee/ugo/i18n"
+)
+
+// localeApp returns an application with English, French and Brazilian Portuguese catalogs
+func localeApp(t *testing.T, urlPrefix bool) *Ugo {
+	bundle, err := i18n.Load(fstest.MapFS{
+		"en.json":    {Data: []byte(`{"validation": {"required": "{field} is required"}}`)},
+		"fr.json":    {Data: []byte(`{"validation": {"required": "{field} est obligatoire"}}`)},
+		"pt-BR.json": {Data: []byte(`{"validation": {"required": "{field} é obrigatório"}}`)},
+	}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Ugo{I18n: bundle}
+	u.Config.Locale.Cookie = "lang"
+	u.Config.Locale.URLPrefix = urlPrefix
+	return u
+}
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		name, path, cookie, accept string
+		urlPrefix                  bool
+		lang, routed               string
+	}{
+		{"default", "/about", "", "", true, "en", "/about"},
+		{"accept_language", "/about", "", "de, fr;q=0.8", true, "fr", "/about"},
+		{"accept_language_unsupported", "/about", "", "de", true, "en", "/about"},
+		{"cookie_before_accept_language", "/about", "fr", "pt-BR", true, "fr", "/about"},
+		{"unsupported_cookie", "/about", "de", "pt-BR", true, "pt-BR", "/about"},
+		{"prefix_before_cookie", "/pt-BR/about", "fr", "en", true, "pt-BR", "/about"},
+		{"prefix_any_case", "/FR/about", "", "", true, "fr", "/about"},
+		{"unsupported_prefix", "/de/about", "fr", "", true, "fr", "/de/about"},
+		{"prefix_disabled", "/fr/about", "", "pt-BR", false, "pt-BR", "/fr/about"},
+	}
+
+	for _, tt := range tests {
+		u := localeApp(t, tt.urlPrefix)
+
+		var lang, routed string
+		h := u.Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang, routed = u.Translator(r).Lang, r.URL.Path
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept-Language", tt.accept)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if lang != tt.lang || rr.Header().Get("Content-Language") != tt.lang {
+			t.Errorf("%s: expected %s; got %s with Content-Language %s", tt.name, tt.lang, lang, rr.Header().Get("Content-Language"))
+		}
+		if routed != tt.routed {
+			t.Errorf("%s: expected %s to be routed; got %s", tt.name, tt.routed, routed)
+		}
+		if req.URL.Path != tt.path {
+			t.Errorf("%s: expected the request of the caller to be left alone; got %s", tt.name, req.URL.Path)
+		}
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	u := localeApp(t, false)
+
+	rr := httptest.NewRecorder()
+	if err := u.SetLocale(rr, "pt-br"); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "lang" || cookies[0].Value != "pt-BR" {
+		t.Errorf("expected a lang cookie of pt-BR; got %v", cookies)
+	}
+
+	if err := u.SetLocale(httptest.NewRecorder(), "de"); err == nil {
+		t.Error("expected an error for a language without a catalog")
+	}
+}
+
+func TestTranslator_WithoutLocale(t *testing.T) {
+	u := localeApp(t, false)
+
+	if lang := u.Translator(httptest.NewRequest(http.MethodGet, "/", nil)).Lang; lang != "en" {
+		t.Errorf("expected the default language without the Locale middleware; got %s", lang)
+	}
+}
+
+func TestValidatorFor(t *testing.T) {
+	u := localeApp(t, false)
+	data := url.Values{"name": {""}, "age": {"old"}}
+
+	tests := []struct {
+		accept, required string
+	}{
+		{"fr", "name est obligatoire"},
+		{"pt-BR", "name é obrigatório"},
+		{"de", "name is required"},
+	}
+
+	for _, tt := range tests {
+		var v *Validation
+		h := u.Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v = u.ValidatorFor(r, data)
+			v.Required(r, "name")
+			v.IsInt("age", data.Get("age"))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept-Language", tt.accept)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if v.Errors["name"] != tt.required {
+			t.Errorf("%s: expected %q; got %q", tt.accept, tt.required, v.Errors["name"])
+		}
+
+		// a message no catalog translates stays in English
+		if v.Errors["age"] != "This field must be an integer" {
+			t.Errorf("%s: expected the English message; got %q", tt.accept, v.Errors["age"])
+		}
+	}
+
+	// without the Locale middleware, messages are in the default language
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	v := u.ValidatorFor(r, data)
+	v.Required(r, "name")
+	if v.Errors["name"] != "name is required" {
+		t.Errorf("expected the message in the default language; got %q", v.Errors["name"])
+	}
+
+	// without catalogs, messages are in English
+	v = (&Ugo{}).Validator(data)
+	v.Required(r, "name")
+	if v.Errors["name"] != "this field cannot be blank" {
+		t.Errorf("expected the English message; got %q", v.Errors["name"])
+	}
+}
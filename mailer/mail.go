@@ -2,22 +2,29 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/ainsleyclark/go-mail/drivers"
 	apimail "github.com/ainsleyclark/go-mail/mail"
+	"github.com/joefazee/ugo/i18n"
 	"github.com/vanng822/go-premailer/premailer"
 	"github.com/xhit/go-simple-mail/v2"
 	"html/template"
+	"io/fs"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Mail struct {
 	Domain      string
 	Templates   string
+	FS          fs.FS // when set, templates are read from FS instead of the Templates folder
 	Host        string
 	Port        int
 	Username    string
@@ -25,11 +32,14 @@ type Mail struct {
 	Encryption  string
 	FromAddress string
 	FromName    string
-	Jobs        chan Message // channel of jobs to send
-	Result      chan Result  // channel of results from sending jobs
+	Jobs        chan Message  // channel of jobs to send
+	Result      chan Result   // channel of results from sending jobs
+	Done        chan struct{} // closed once ListenForMail has drained Jobs and returned
 	API         string
 	APIKey      string
 	APIURL      string
+
+	shutdown sync.Once
 }
 
 type Message struct {
@@ -40,6 +50,7 @@ type Message struct {
 	Template    string
 	Attachments []string
 	Data        interface{}
+	Lang        string // reads the templates in the folder of the language, such as mail/fr, when they exist
 }
 
 type Result struct {
@@ -47,10 +58,10 @@ type Result struct {
 	Error   error
 }
 
+// ListenForMail sends every message received on Jobs until the channel is closed
 func (m *Mail) ListenForMail() {
 	log.Println("Mail service started running")
-	for {
-		msg := <-m.Jobs
+	for msg := range m.Jobs {
 		err := m.Send(msg)
 		if err != nil {
 			m.Result <- Result{Success: false, Error: err}
@@ -58,6 +69,33 @@ func (m *Mail) ListenForMail() {
 			m.Result <- Result{Success: true, Error: nil}
 		}
 	}
+
+	if m.Done != nil {
+		close(m.Done)
+	}
+}
+
+// Shutdown closes the Jobs channel and waits for the messages already queued to be sent.
+// Results are discarded while it waits, so a full Result channel nobody reads does not hold up
+// the queue. Nothing may be sent on Jobs after Shutdown has been called; calling it again only waits
+func (m *Mail) Shutdown(ctx context.Context) error {
+	m.shutdown.Do(func() {
+		close(m.Jobs)
+	})
+
+	if m.Done == nil {
+		return nil
+	}
+
+	for {
+		select {
+		case <-m.Done:
+			return nil
+		case <-m.Result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func (m *Mail) Send(msg Message) error {
@@ -116,9 +154,7 @@ func (m *Mail) SendSMTPMessage(msg Message) error {
 }
 
 func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
-	templateToRender := fmt.Sprintf("%s/%s.html.tmpl", m.Templates, msg.Template)
-
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := m.parseTemplate(msg.Template+".html.tmpl", msg.Lang)
 	if err != nil {
 		return "", err
 	}
@@ -141,9 +177,7 @@ func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
 
 func (m *Mail) buildPlainTextMessage(msg Message) (string, error) {
 
-	templateToRender := fmt.Sprintf("%s/%s.plain.txt", m.Templates, msg.Template)
-
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := m.parseTemplate(msg.Template+".plain.txt", msg.Lang)
 	if err != nil {
 		return "", err
 	}
@@ -159,6 +193,41 @@ func (m *Mail) buildPlainTextMessage(msg Message) (string, error) {
 	return plainMessage, nil
 }
 
+// parseTemplate parses a mail template from FS, or from the Templates folder. The template
+// of lang is preferred, then that of its base language, so a pt-BR message reads pt-BR/name,
+// then pt/name, then name
+func (m *Mail) parseTemplate(name, lang string) (*template.Template, error) {
+	name = m.localized(name, lang)
+
+	if m.FS != nil {
+		return template.New("email-html").ParseFS(m.FS, name)
+	}
+	return template.New("email-html").ParseFiles(fmt.Sprintf("%s/%s", m.Templates, name))
+}
+
+func (m *Mail) localized(name, lang string) string {
+	if lang == "" {
+		return name
+	}
+
+	// folders are named the way i18n names catalogs, so pt_br and PT-br read mail/pt-BR
+	lang = i18n.Normalize(lang)
+	base, _, _ := strings.Cut(lang, "-")
+	for _, dir := range []string{lang, base} {
+		var err error
+		if m.FS != nil {
+			_, err = fs.Stat(m.FS, dir+"/"+name)
+		} else {
+			_, err = os.Stat(fmt.Sprintf("%s/%s/%s", m.Templates, dir, name))
+		}
+		if err == nil {
+			return dir + "/" + name
+		}
+	}
+
+	return name
+}
+
 func (m *Mail) getEncryption(encryption string) mail.Encryption {
 
 	switch encryption {
@@ -198,6 +267,8 @@ func (m *Mail) inlineCSS(s string) (string, error) {
 
 func (m *Mail) ChooseAPI(msg Message) error {
 	switch m.API {
+	case "smtp":
+		return m.SendSMTPMessage(msg)
 	case "mailgun", "sparkpost", "sendgrid":
 		return m.SendUsingAPI(msg, m.API)
 	default:
@@ -1,8 +1,12 @@
 package mailer
 
 import (
+	"context"
 	"errors"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func getDemoMessage() Message {
@@ -82,6 +86,54 @@ func TestMail_BuildPlainMessage(t *testing.T) {
 	}
 }
 
+func TestMail_BuildMessageFromFS(t *testing.T) {
+
+	m := Mail{FS: os.DirFS("./testdata/mail")}
+	msg := getDemoMessage()
+
+	_, err := m.buildHTMLMessage(msg)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = m.buildPlainTextMessage(msg)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMail_BuildLocalizedMessage(t *testing.T) {
+
+	m := Mail{Templates: "./testdata/mail"}
+
+	for lang, expected := range map[string]string{
+		"":      "Plain message goes here",
+		"fr":    "Le message en texte brut",
+		"fr-CA": "Le message en texte brut",
+		"de":    "Plain message goes here",
+		"FR":    "Le message en texte brut",
+		"pt_BR": "A mensagem em texto simples",
+		"PT-br": "A mensagem em texto simples",
+		"pt":    "Plain message goes here",
+	} {
+		msg := getDemoMessage()
+		msg.Lang = lang
+
+		plain, err := m.buildPlainTextMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(plain, expected) {
+			t.Errorf("%q: expected %q; got %q", lang, expected, plain)
+		}
+
+		// there is no french html template, so every language gets the default one
+		if _, err := m.buildHTMLMessage(msg); err != nil {
+			t.Errorf("%q: %s", lang, err)
+		}
+	}
+}
+
 func TestMail_Send(t *testing.T) {
 
 	msg := getDemoMessage()
@@ -115,3 +167,55 @@ func TestMail_ChooseAPI(t *testing.T) {
 		t.Error("we should get an error for invalid api")
 	}
 }
+
+func TestMail_Shutdown(t *testing.T) {
+
+	m := Mail{
+		Jobs:   make(chan Message, 1),
+		Result: make(chan Result, 1),
+		Done:   make(chan struct{}),
+	}
+
+	go m.ListenForMail()
+
+	err := m.Shutdown(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	// a second shutdown must not close Jobs again
+	err = m.Shutdown(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case <-m.Done:
+	default:
+		t.Error("expected mail listener to have stopped")
+	}
+}
+
+func TestMail_ShutdownUnreadResults(t *testing.T) {
+
+	m := Mail{
+		Jobs:   make(chan Message, 30),
+		Result: make(chan Result, 20),
+		Done:   make(chan struct{}),
+	}
+
+	// more messages than Result holds, each failing at once on a missing template
+	for i := 0; i < 30; i++ {
+		m.Jobs <- Message{Template: "missing"}
+	}
+
+	go m.ListenForMail()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := m.Shutdown(ctx)
+	if err != nil {
+		t.Errorf("expected the queue to drain although nobody reads Result; got %v", err)
+	}
+}
@@ -1,28 +1,32 @@
 package ugo
 
 import (
+	"github.com/joefazee/ugo/session"
 	"github.com/justinas/nosurf"
 	"net/http"
-	"strconv"
 )
 
 func (u *Ugo) SessionLoad(next http.Handler) http.Handler {
+	next = u.expireRenewedSessions(next)
+
+	// cookie sessions are written by their store, which holds the data rather than a token
+	if store, ok := u.Session.Store.(*session.CookieStore); ok {
+		return store.LoadAndSave(u.Session, next)
+	}
 	return u.Session.LoadAndSave(next)
 }
 
 func (u *Ugo) NoSurf(next http.Handler) http.Handler {
 
 	csrfHandler := nosurf.New(next)
-	secure, _ := strconv.ParseBool(u.config.cookie.secure)
-
 	csrfHandler.ExemptGlob("/api/*")
 
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
-		Secure:   secure,
+		Secure:   u.Config.Cookie.Secure,
 		SameSite: http.SameSiteStrictMode,
-		Domain:   u.config.cookie.domain,
+		Domain:   u.Config.Cookie.Domain,
 	})
 
 	return csrfHandler
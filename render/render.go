@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"github.com/justinas/nosurf"
 	"html/template"
+	"io/fs"
 	"net/http"
+	"os"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
+	"github.com/joefazee/ugo/i18n"
 )
 
 type Render struct {
@@ -20,6 +25,17 @@ type Render struct {
 	ServerName string
 	JetViews   *jet.Set
 	Session    *scs.SessionManager
+	// FuncMap holds the functions available to every Go template
+	FuncMap template.FuncMap
+	// Views is where Go templates are read from, such as an embed.FS. When nil they are read
+	// from the views folder in RootPath
+	Views fs.FS
+	// Debug parses Go templates on every request, so changes show without a restart. Otherwise
+	// each page is parsed once and kept
+	Debug bool
+
+	mu          sync.RWMutex
+	goTemplates map[string]*template.Template
 }
 
 type TemplateData struct {
@@ -34,6 +50,7 @@ type TemplateData struct {
 	Secure          bool
 	Error           string
 	Flash           string
+	Lang            string
 }
 
 func (r *Render) defaultData(td *TemplateData, rq *http.Request) *TemplateData {
@@ -41,7 +58,14 @@ func (r *Render) defaultData(td *TemplateData, rq *http.Request) *TemplateData {
 	td.ServerName = r.ServerName
 	td.CSRFToken = nosurf.Token(rq)
 	td.Port = r.Port
-	if r.Session != nil && r.Session.Exists(rq.Context(), "userID") {
+	if tr := i18n.FromContext(rq.Context()); tr != nil {
+		td.Lang = tr.Lang
+	}
+	if r.Session == nil {
+		return td
+	}
+
+	if r.Session.Exists(rq.Context(), "userID") {
 		td.IsAuthenticated = true
 	}
 
@@ -64,9 +88,12 @@ func (r *Render) Page(w http.ResponseWriter, rq *http.Request, view string, vari
 	return errors.New("invalid template engine specified")
 }
 
-// GoPage renders a template using the standard Go template engine
+// GoPage renders a template using the standard Go template engine. Every *.layout.html and
+// *.partial.html file under views is parsed along with the page, so a page can wrap itself
+// in a layout with {{template "base" .}} and fill the layout's blocks with {{define}}.
+// Templates translate with {{t "key" "name" .Name}}, into the language of the request
 func (r *Render) GoPage(w http.ResponseWriter, rq *http.Request, view string, data interface{}) error {
-	tmpl, err := template.ParseFiles(fmt.Sprintf("%s/views/%s.page.html", r.RootPath, view))
+	tmpl, err := r.goTemplate(view, i18n.FromContext(rq.Context()))
 	if err != nil {
 		return err
 	}
@@ -76,14 +103,115 @@ func (r *Render) GoPage(w http.ResponseWriter, rq *http.Request, view string, da
 		td = data.(*TemplateData)
 	}
 
-	err = tmpl.Execute(w, &td)
+	td = r.defaultData(td, rq)
+
+	err = tmpl.Execute(w, td)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// JetPage render`s a template using the Jet template engine
+// goTemplate returns the parsed page, from the cache unless in debug mode. t is bound to
+// the template when it is parsed, so each language has its own copy
+func (r *Render) goTemplate(view string, tr *i18n.Translator) (*template.Template, error) {
+	if r.Debug {
+		return r.parseGoTemplate(view, tr)
+	}
+
+	var key string
+	if tr != nil {
+		key = tr.Lang + "\x00"
+	}
+	key += view
+
+	// the root path is part of the key, as it may point elsewhere when views are on disk
+	if r.Views == nil {
+		key = r.RootPath + "\x00" + key
+	}
+
+	r.mu.RLock()
+	tmpl, ok := r.goTemplates[key]
+	r.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := r.parseGoTemplate(view, tr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.goTemplates == nil {
+		r.goTemplates = make(map[string]*template.Template)
+	}
+	r.goTemplates[key] = tmpl
+	r.mu.Unlock()
+
+	return tmpl, nil
+}
+
+// parseGoTemplate parses a page with the layouts and partials. The page is parsed last, so
+// its definitions replace the default content of the layout's blocks
+func (r *Render) parseGoTemplate(view string, tr *i18n.Translator) (*template.Template, error) {
+	views := r.viewsFS()
+	page := view + ".page.html"
+
+	shared, err := goSharedFiles(views)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := template.New(path.Base(page)).Funcs(template.FuncMap{"t": tr.T}).Funcs(r.FuncMap)
+	if len(shared) > 0 {
+		if tmpl, err = tmpl.ParseFS(views, shared...); err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl.ParseFS(views, page)
+}
+
+// HasView reports whether the page template for view exists for the selected engine
+func (r *Render) HasView(view string) bool {
+	ext := ".page.html"
+	if strings.ToLower(r.Renderer) == "jet" {
+		ext = ".page.jet"
+	}
+
+	info, err := fs.Stat(r.viewsFS(), view+ext)
+	return err == nil && !info.IsDir()
+}
+
+func (r *Render) viewsFS() fs.FS {
+	if r.Views != nil {
+		return r.Views
+	}
+	return os.DirFS(r.RootPath + "/views")
+}
+
+// goSharedFiles lists the layouts and partials in views
+func goSharedFiles(views fs.FS) ([]string, error) {
+	var files []string
+
+	err := fs.WalkDir(views, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if !d.IsDir() && (strings.HasSuffix(name, ".layout.html") || strings.HasSuffix(name, ".partial.html")) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// JetPage render`s a template using the Jet template engine. Templates translate with
+// {{ t("key", "name", .Name) }}, into the language of the request
 func (r *Render) JetPage(w http.ResponseWriter, rq *http.Request, view string, variables, data interface{}) error {
 
 	var vars jet.VarMap
@@ -100,6 +228,10 @@ func (r *Render) JetPage(w http.ResponseWriter, rq *http.Request, view string, v
 
 	td = r.defaultData(td, rq)
 
+	if _, ok := vars["t"]; !ok {
+		vars.Set("t", i18n.FromContext(rq.Context()).T)
+	}
+
 	t, err := r.JetViews.GetTemplate(fmt.Sprintf("%s.page.jet", view))
 	if err != nil {
 		return err
@@ -1,9 +1,19 @@
 package render
 
 import (
+	"bytes"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/joefazee/ugo/i18n"
+	"github.com/justinas/nosurf"
 )
 
 var pageData = []struct {
@@ -101,3 +111,187 @@ func TestRender_GoPage(t *testing.T) {
 	}
 
 }
+
+// renderWithSession renders view with renderer behind the session and CSRF middleware,
+// putting the flash message and logging a user in first when loggedIn is set
+func renderWithSession(t *testing.T, renderer, view string, loggedIn bool) string {
+	sm := scs.New()
+
+	rd := &Render{
+		Renderer: renderer,
+		RootPath: "./testdata",
+		JetViews: views,
+		Session:  sm,
+		FuncMap:  template.FuncMap{"upper": strings.ToUpper},
+	}
+
+	var rendered bytes.Buffer
+	h := sm.LoadAndSave(nosurf.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sm.Put(r.Context(), "flash", "saved")
+		if loggedIn {
+			sm.Put(r.Context(), "userID", 1)
+		}
+
+		if err := rd.Page(&responseBuffer{ResponseWriter: w, buf: &rendered}, r, view, nil, nil); err != nil {
+			t.Errorf("%s: %s", renderer, err)
+		}
+	})))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/dashboard", nil))
+
+	// the engines differ in whitespace, and in how they escape the CSRF token
+	out := strings.Join(strings.Fields(rendered.String()), " ")
+	return csrfValue.ReplaceAllString(out, `<input value="token">`)
+}
+
+var csrfValue = regexp.MustCompile(`<input value="[^"]+">`)
+
+type responseBuffer struct {
+	http.ResponseWriter
+	buf *bytes.Buffer
+}
+
+func (rb *responseBuffer) Write(b []byte) (int, error) {
+	return rb.buf.Write(b)
+}
+
+func TestRender_GoPage_Parity(t *testing.T) {
+
+	for _, loggedIn := range []bool{true, false} {
+		goOut := renderWithSession(t, "go", "dashboard", loggedIn)
+		jetOut := renderWithSession(t, "jet", "dashboard", loggedIn)
+
+		if goOut == "" || goOut != jetOut {
+			t.Errorf("go and jet renderers differ:\n go: %s\njet: %s", goOut, jetOut)
+		}
+
+		for _, want := range []string{"<title>Dashboard</title>", "<p>SAVED</p>", `<input value="token">`} {
+			if !strings.Contains(goOut, want) {
+				t.Errorf("expected %q in %s", want, goOut)
+			}
+		}
+		nav := "<nav>Login</nav>"
+		if loggedIn {
+			nav = "<nav>Logout</nav>"
+		}
+		if !strings.Contains(goOut, nav) {
+			t.Errorf("expected %q in %s", nav, goOut)
+		}
+	}
+}
+
+func TestRender_GoPage_NoLayouts(t *testing.T) {
+
+	rd := &Render{Renderer: "go", RootPath: "./testdata/nolayouts"}
+
+	w := httptest.NewRecorder()
+	err := rd.Page(w, httptest.NewRequest("GET", "/", nil), "home", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Body.String() != "Hello Go" {
+		t.Errorf("expected Hello Go; got %q", w.Body.String())
+	}
+}
+
+func TestRender_GoPage_FS(t *testing.T) {
+
+	views := fstest.MapFS{
+		"home.page.html":               {Data: []byte(`{{template "base" .}}{{define "body"}}embedded{{end}}`)},
+		"layouts/base.layout.html":     {Data: []byte(`{{define "base"}}<main>{{block "body" .}}{{end}}</main>{{end}}`)},
+		"partials/unused.partial.html": {Data: []byte(`{{define "unused"}}{{end}}`)},
+	}
+
+	rd := &Render{Renderer: "go", Views: views}
+
+	w := httptest.NewRecorder()
+	err := rd.Page(w, httptest.NewRequest("GET", "/", nil), "home", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Body.String() != "<main>embedded</main>" {
+		t.Errorf("expected the page from the FS; got %q", w.Body.String())
+	}
+}
+
+func TestRender_GoPage_Cache(t *testing.T) {
+
+	views := fstest.MapFS{
+		"home.page.html": {Data: []byte("first")},
+	}
+
+	render := func(rd *Render) string {
+		w := httptest.NewRecorder()
+		if err := rd.GoPage(w, httptest.NewRequest("GET", "/", nil), "home", nil); err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+
+	cached := &Render{Views: views}
+	debug := &Render{Views: views, Debug: true}
+
+	render(cached)
+	views["home.page.html"] = &fstest.MapFile{Data: []byte("second")}
+
+	if got := render(cached); got != "first" {
+		t.Errorf("expected the cached template; got %q", got)
+	}
+	if got := render(debug); got != "second" {
+		t.Errorf("expected the template to be parsed again in debug mode; got %q", got)
+	}
+}
+
+func TestRender_HasView(t *testing.T) {
+
+	tests := []struct {
+		renderer, view string
+		expected       bool
+	}{
+		{"go", "greeting", true},
+		{"jet", "greeting", false},
+		{"jet", "home", true},
+		{"go", "layouts", false},
+		{"go", "errors/404", false},
+	}
+
+	for _, tt := range tests {
+		rd := &Render{Renderer: tt.renderer, RootPath: "./testdata"}
+		if got := rd.HasView(tt.view); got != tt.expected {
+			t.Errorf("%s %s: expected %v; got %v", tt.renderer, tt.view, tt.expected, got)
+		}
+	}
+}
+
+func TestRender_Translate(t *testing.T) {
+
+	bundle, err := i18n.Load(os.DirFS("./testdata/lang"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, renderer := range []string{"go", "jet"} {
+		// one renderer for both languages, so the go templates of each are cached apart
+		rd := &Render{Renderer: renderer, RootPath: "./testdata", JetViews: views}
+
+		for lang, expected := range map[string]string{
+			"en": `<p lang="en">Welcome, Ada 0 messages</p>`,
+			"fr": `<p lang="fr">Bienvenue, Ada 0 message</p>`,
+		} {
+			r := httptest.NewRequest("GET", "/", nil)
+			r = r.WithContext(i18n.NewContext(r.Context(), bundle.Translator(lang)))
+			w := httptest.NewRecorder()
+
+			td := &TemplateData{Data: map[string]interface{}{"name": "Ada"}}
+			if err := rd.Page(w, r, "translated", nil, td); err != nil {
+				t.Fatalf("%s %s: %s", renderer, lang, err)
+			}
+
+			if got := strings.TrimSpace(w.Body.String()); got != expected {
+				t.Errorf("%s %s: expected %s; got %s", renderer, lang, expected, got)
+			}
+		}
+	}
+}
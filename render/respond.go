@@ -0,0 +1,191 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the formats Respond can render, by the name used in ?format=
+var formats = map[string]string{
+	"html": "text/html",
+	"json": "application/json",
+	"xml":  "application/xml",
+}
+
+// Respond renders view for browsers, and the Data of data as JSON or XML for API clients,
+// choosing by the Accept header. A format query parameter of html, json or xml overrides
+// the header. An empty view only offers JSON and XML. When nothing acceptable can be
+// rendered, Respond replies 406 Not Acceptable
+func (r *Render) Respond(w http.ResponseWriter, rq *http.Request, view string, data interface{}) error {
+	w.Header().Add("Vary", "Accept")
+
+	var offers []string
+	if view != "" {
+		offers = append(offers, "html")
+	}
+	offers = append(offers, "json", "xml")
+
+	format := negotiate(rq, offers)
+
+	td := &TemplateData{}
+	if data != nil {
+		td = data.(*TemplateData)
+	}
+
+	switch format {
+	case "html":
+		return r.Page(w, rq, view, nil, td)
+	case "json":
+		out, err := json.MarshalIndent(td.Data, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		return err
+	case "xml":
+		out, err := xml.MarshalIndent(xmlMap(td.Data), "", "\t")
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, err = w.Write(out)
+		return err
+	default:
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+}
+
+// negotiate returns the offered format the client prefers, or "" if it accepts none of them
+func negotiate(rq *http.Request, offers []string) string {
+	if format := strings.ToLower(rq.URL.Query().Get("format")); format != "" {
+		for _, offer := range offers {
+			if offer == format {
+				return offer
+			}
+		}
+		return ""
+	}
+
+	accept := rq.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := acceptQuality(ranges, formats[offer])
+		if offer == "xml" {
+			if textQ := acceptQuality(ranges, "text/xml"); textQ > q {
+				q = textQ
+			}
+		}
+
+		// offers are in order of preference, so a tie goes to the earlier one
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(fields[0])), "/")
+		if !ok {
+			continue
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(name) == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality the client gives mediaType, taken from the most specific
+// range that matches it
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		}
+
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+
+	return q
+}
+
+// xmlMap encodes a map as an element per key, in key order, as encoding/xml cannot
+// encode maps
+type xmlMap map[string]interface{}
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "data"}
+	return encodeMap(e, start, m)
+}
+
+func encodeMap(e *xml.Encoder, start xml.StartElement, m map[string]interface{}) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		element := xml.StartElement{Name: xml.Name{Local: key}}
+
+		var err error
+		if nested, ok := m[key].(map[string]interface{}); ok {
+			err = encodeMap(e, element, nested)
+		} else {
+			err = e.EncodeElement(m[key], element)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
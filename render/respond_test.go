@@ -0,0 +1,71 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var respondData = []struct {
+	name        string
+	view        string
+	target      string
+	accept      string
+	status      int
+	contentType string
+	body        string
+}{
+	{"browser", "greeting", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", http.StatusOK, "", "<h1>Hello Ada</h1>"},
+	{"no_accept", "greeting", "/", "", http.StatusOK, "", "<h1>Hello Ada</h1>"},
+	{"json", "greeting", "/", "application/json", http.StatusOK, "application/json", `"name": "Ada"`},
+	{"xml", "greeting", "/", "application/xml", http.StatusOK, "application/xml", "<data><name>Ada</name><user><id>7</id></user></data>"},
+	{"text_xml", "greeting", "/", "text/xml", http.StatusOK, "application/xml", "<name>Ada</name>"},
+	{"quality", "greeting", "/", "application/xml;q=0.5, application/json", http.StatusOK, "application/json", `"name": "Ada"`},
+	{"excluded", "greeting", "/", "text/html;q=0, */*", http.StatusOK, "application/json", `"name": "Ada"`},
+	{"wildcard_no_view", "", "/", "*/*", http.StatusOK, "application/json", `"name": "Ada"`},
+	{"format_override", "greeting", "/?format=xml", "text/html", http.StatusOK, "application/xml", "<name>Ada</name>"},
+	{"format_html", "greeting", "/?format=html", "application/json", http.StatusOK, "", "<h1>Hello Ada</h1>"},
+	{"not_acceptable", "greeting", "/", "image/png", http.StatusNotAcceptable, "", "Not Acceptable"},
+	{"html_without_view", "", "/", "text/html", http.StatusNotAcceptable, "", "Not Acceptable"},
+	{"unknown_format", "greeting", "/?format=csv", "", http.StatusNotAcceptable, "", "Not Acceptable"},
+}
+
+func TestRender_Respond(t *testing.T) {
+
+	for _, tt := range respondData {
+		rd := &Render{Renderer: "go", RootPath: "./testdata"}
+
+		r := httptest.NewRequest("GET", tt.target, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+
+		td := &TemplateData{Data: map[string]interface{}{
+			"name": "Ada",
+			"user": map[string]interface{}{"id": 7},
+		}}
+
+		err := rd.Respond(w, r, tt.view, td)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d; got %d", tt.name, tt.status, w.Code)
+		}
+		if tt.contentType != "" && w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: expected %s; got %s", tt.name, tt.contentType, w.Header().Get("Content-Type"))
+		}
+
+		body := strings.Join(strings.Fields(w.Body.String()), "")
+		if !strings.Contains(body, strings.Join(strings.Fields(tt.body), "")) {
+			t.Errorf("%s: expected %q in %q", tt.name, tt.body, w.Body.String())
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s: expected Vary: Accept", tt.name)
+		}
+	}
+}
@@ -104,6 +104,12 @@ func (u *Ugo) ErrorForbidden(w http.ResponseWriter) {
 	u.ErrorStatus(w, http.StatusForbidden)
 }
 
+// ErrorStatus replies with the error page for status, or with plain text when w is not
+// serving a request that went through the routes of the application
 func (u *Ugo) ErrorStatus(w http.ResponseWriter, status int) {
+	if r := requestOf(w); r != nil {
+		u.ErrorPage(w, r, status, nil)
+		return
+	}
 	http.Error(w, http.StatusText(status), status)
 }
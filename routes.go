@@ -17,10 +17,35 @@ func (u *Ugo) routes() http.Handler {
 		mux.Use(middleware.Logger)
 	}
 
-	mux.Use(middleware.Recoverer)
+	// before Locale, so a panic anywhere after the logger gets an error page
+	mux.Use(u.Recoverer)
+	mux.Use(u.Locale)
 	mux.Use(u.SessionLoad)
+	mux.Use(u.Sessions.Track)
 	mux.Use(u.NoSurf)
+	mux.Use(u.keepRequest)
+
+	mux.NotFound(u.NotFound)
+	mux.MethodNotAllowed(u.MethodNotAllowed)
 
 	return mux
 
 }
+
+// handler is what ListenAndServe serves: Routes, and the cache statistics when
+// CACHE_METRICS_PATH is set. The statistics are served here rather than from a route, as
+// chi refuses middleware added to Routes after its first route
+func (u *Ugo) handler() http.Handler {
+	if u.cacheMetrics == nil {
+		return u.Routes
+	}
+
+	metrics := u.cacheMetrics.Handler(u.Config.CacheMetricsToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == u.Config.CacheMetrics {
+			metrics.ServeHTTP(w, r)
+			return
+		}
+		u.Routes.ServeHTTP(w, r)
+	})
+}
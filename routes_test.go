@@ -0,0 +1,70 @@
+package ugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/joefazee/ugo/cache"
+	"github.com/joefazee/ugo/i18n"
+	"github.com/joefazee/ugo/session"
+)
+
+func TestHandler_CacheMetrics(t *testing.T) {
+	sm := scs.New()
+	bundle, err := i18n.Load(fstest.MapFS{}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &Ugo{
+		Session:      sm,
+		Sessions:     session.NewRegistry(sm, []byte(testKey)),
+		I18n:         bundle,
+		cacheMetrics: cache.NewInstrumentedCache(cache.NewMemoryCache(0, 0)),
+	}
+	u.Config.CacheMetrics = "/metrics"
+	u.Config.CacheMetricsToken = "metrics-token"
+	u.Routes = u.routes().(*chi.Mux)
+
+	// the application can still add middleware, which chi refuses once a route is registered
+	u.Routes.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Middleware", "ran")
+			next.ServeHTTP(w, r)
+		})
+	})
+	u.Routes.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Middleware", r.Header.Get("X-Middleware"))
+	})
+	h := u.handler()
+
+	tests := []struct {
+		name, path, auth string
+		status           int
+	}{
+		{"routes", "/", "", http.StatusOK},
+		{"metrics_without_token", "/metrics", "", http.StatusUnauthorized},
+		{"metrics", "/metrics", "Bearer metrics-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", tt.auth)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("%s: expected %d; got %d", tt.name, tt.status, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Header().Get("X-Middleware") != "ran" {
+		t.Error("expected the routes the application has when serving, with its middleware")
+	}
+}
@@ -0,0 +1,71 @@
+package ugo
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// sessionDeadlineKey holds the deadline a renewed session had before it was renewed, as scs
+// gives every new token a full lifetime
+const sessionDeadlineKey = "_ugo_deadline"
+
+// LoginSession gives the session a new token and stores userID in it. Renewing the token
+// when the user logs in stops session fixation, as a token planted before the login is
+// worth nothing after it. The session gets a full lifetime from the login
+func (u *Ugo) LoginSession(ctx context.Context, userID interface{}) error {
+	if err := u.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+
+	u.Session.Remove(ctx, sessionDeadlineKey)
+	u.Session.Put(ctx, u.Sessions.UserKey, userID)
+	return nil
+}
+
+// LogoutSession ends the session, and starts an empty one under a new token, so a flash
+// message can still be shown after logging out
+func (u *Ugo) LogoutSession(ctx context.Context) error {
+	if err := u.Session.Destroy(ctx); err != nil {
+		return err
+	}
+
+	return u.Session.RenewToken(ctx)
+}
+
+// RenewSession gives the session a new token and keeps its data. Call it whenever the
+// privileges of the user change, such as when they are given a new role. The session still
+// ends when it would have without the renewal, so renewing cannot extend the absolute timeout
+func (u *Ugo) RenewSession(ctx context.Context) error {
+	deadline := u.sessionDeadline(ctx)
+
+	if err := u.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+
+	u.Session.Put(ctx, sessionDeadlineKey, deadline.UnixNano())
+	return nil
+}
+
+// sessionDeadline is the time the session in ctx ends, however often it has been renewed
+func (u *Ugo) sessionDeadline(ctx context.Context) time.Time {
+	if deadline := u.Session.GetInt64(ctx, sessionDeadlineKey); deadline != 0 {
+		return time.Unix(0, deadline)
+	}
+	return u.Session.Deadline(ctx)
+}
+
+// expireRenewedSessions logs out a renewed session once the deadline it had before it was
+// renewed has passed
+func (u *Ugo) expireRenewedSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if u.Session.Exists(ctx, sessionDeadlineKey) && !time.Now().Before(u.sessionDeadline(ctx)) {
+			if err := u.LogoutSession(ctx); err != nil {
+				u.ErrorLog.Println("error ending expired session:", err)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// BadgerStore is an scs.Store backed by a badger database. Sessions are stored with a TTL,
+// so badger drops them when they expire and no cleanup goroutine is needed
+type BadgerStore struct {
+	db     *badger.DB
+	prefix string
+}
+
+// NewBadgerStore returns a BadgerStore that keeps sessions in db
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{db: db, prefix: "scs:session:"}
+}
+
+// Find returns the data for a session token. exists is false if the token is unknown or expired
+func (s *BadgerStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(s.prefix + token))
+		if err != nil {
+			return err
+		}
+
+		b, err = item.ValueCopy(nil)
+		return err
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Commit adds or replaces the data for a session token. A session that has already expired
+// is deleted instead
+func (s *BadgerStore) Commit(token string, b []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.Delete(token)
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(s.prefix+token), b).WithTTL(ttl))
+	})
+}
+
+// Delete removes a session token and its data
+func (s *BadgerStore) Delete(token string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(s.prefix + token))
+	})
+}
+
+// All returns the data of every session that has not expired, keyed by token
+func (s *BadgerStore) All() (map[string][]byte, error) {
+	sessions := make(map[string][]byte)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(s.prefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			sessions[string(item.Key()[len(prefix):])] = data
+		}
+		return nil
+	})
+
+	return sessions, err
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+func TestBadgerStore(t *testing.T) {
+
+	db, err := badger.Open(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewBadgerStore(db)
+
+	err = store.Commit("foo", []byte("bar"), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	b, found, err := store.Find("foo")
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || string(b) != "bar" {
+		t.Errorf("expected to find bar for foo; got %q %v", b, found)
+	}
+
+	err = store.Commit("expired", []byte("bar"), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, found, err = store.Find("expired")
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("expired session should not be found")
+	}
+
+	all, err := store.All()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(all) != 1 || string(all["foo"]) != "bar" {
+		t.Errorf("expected 1 live session; got %v", all)
+	}
+
+	err = store.Delete("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, found, err = store.Find("foo")
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("deleted session should not be found")
+	}
+}
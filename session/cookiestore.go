@@ -0,0 +1,331 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const (
+	// maxCookieSize is the largest cookie, name and attributes included, browsers must accept
+	maxCookieSize = 4096
+	// maxCookieChunks limits how many cookies one session may be split across
+	maxCookieChunks = 10
+)
+
+var (
+	// ErrCookieTooLarge is returned when the sealed session does not fit in maxCookieChunks cookies
+	ErrCookieTooLarge = errors.New("session: session data is too large for a cookie")
+
+	errNoCookieKey      = errors.New("session: the cookie store has no encryption key")
+	errCookieMiddleware = errors.New("session: the cookie store must be used with CookieStore.LoadAndSave")
+)
+
+// CookieStore is a stateless scs.Store that keeps the session data in the client's cookies,
+// sealed with AES-GCM. The first key seals new sessions, and every key is tried when opening
+// one, so a key can be rotated by putting the new key first and keeping the old one until
+// the sessions it sealed have expired. A cookie that has been tampered with, has expired or
+// was sealed with an unknown key is treated as no session at all.
+//
+// The store only learns the sealed value when a session is committed, so sessions must be
+// loaded and saved with LoadAndSave instead of scs.SessionManager.LoadAndSave. Sessions
+// cannot be revoked on the server: Destroy only clears the cookies of the current client
+type CookieStore struct {
+	aeads []cipher.AEAD
+}
+
+// NewCookieStore returns a CookieStore that seals sessions with keys[0] and opens them with
+// any of keys. Each key is stretched with HMAC-SHA256 into an AES-256 key used for nothing else
+func NewCookieStore(keys ...string) *CookieStore {
+	c := &CookieStore{}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte("ugo session cookie"))
+
+		// a 32 byte key is always valid for AES and GCM
+		block, _ := aes.NewCipher(mac.Sum(nil))
+		aead, _ := cipher.NewGCM(block)
+		c.aeads = append(c.aeads, aead)
+	}
+	return c
+}
+
+// cookieState carries the sealed session between CommitCtx and LoadAndSave
+type cookieState struct {
+	sealed string
+	expiry time.Time
+	// stale is set when the cookie was opened with a key other than the first
+	stale bool
+}
+
+type cookieStateKey struct{}
+
+func stateFrom(ctx context.Context) *cookieState {
+	state, _ := ctx.Value(cookieStateKey{}).(*cookieState)
+	return state
+}
+
+// seal encrypts the expiry and data with the first key
+func (c *CookieStore) seal(b []byte, expiry time.Time) (string, error) {
+	if len(c.aeads) == 0 {
+		return "", errNoCookieKey
+	}
+	aead := c.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	plain := make([]byte, 8, 8+len(b))
+	binary.BigEndian.PutUint64(plain, uint64(expiry.UnixNano()))
+	plain = append(plain, b...)
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// open decrypts a sealed value, reporting which key opened it. ok is false for anything that
+// cannot be authenticated or has expired
+func (c *CookieStore) open(sealed string) (b []byte, key int, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	for i, aead := range c.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+		if err != nil || len(plain) < 8 {
+			continue
+		}
+
+		expiry := time.Unix(0, int64(binary.BigEndian.Uint64(plain)))
+		if time.Now().After(expiry) {
+			return nil, 0, false
+		}
+		return plain[8:], i, true
+	}
+
+	return nil, 0, false
+}
+
+// Find opens a sealed session. token is the sealed value read from the cookies
+func (c *CookieStore) Find(token string) ([]byte, bool, error) {
+	b, _, ok := c.open(token)
+	return b, ok, nil
+}
+
+// FindCtx is Find, and also notes when the session should be sealed again with the current key
+func (c *CookieStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	b, key, ok := c.open(token)
+	if ok && key > 0 {
+		if state := stateFrom(ctx); state != nil {
+			state.stale = true
+		}
+	}
+	return b, ok, nil
+}
+
+// Commit always fails, as there is no request to write the cookie to
+func (c *CookieStore) Commit(token string, b []byte, expiry time.Time) error {
+	return errCookieMiddleware
+}
+
+// CommitCtx seals the session for LoadAndSave to write. token is not used, as the cookie
+// holds the data itself
+func (c *CookieStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	state := stateFrom(ctx)
+	if state == nil {
+		return errCookieMiddleware
+	}
+
+	sealed, err := c.seal(b, expiry)
+	if err != nil {
+		return err
+	}
+
+	state.sealed, state.expiry = sealed, expiry
+	return nil
+}
+
+// Delete does nothing; LoadAndSave clears the cookies of a destroyed session
+func (c *CookieStore) Delete(token string) error {
+	return nil
+}
+
+func (c *CookieStore) DeleteCtx(ctx context.Context, token string) error {
+	return nil
+}
+
+// LoadAndSave loads the session from the request's cookies and writes it back when it
+// changes, as sm.LoadAndSave does for stores that keep the data on the server
+func (c *CookieStore) LoadAndSave(sm *scs.SessionManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := &cookieState{}
+		ctx := context.WithValue(r.Context(), cookieStateKey{}, state)
+
+		ctx, err := sm.Load(ctx, readChunks(r, sm.Cookie.Name))
+		if err != nil {
+			sm.ErrorFunc(w, r, err)
+			return
+		}
+
+		sr := r.WithContext(ctx)
+		bw := &bufferedWriter{ResponseWriter: w}
+		next.ServeHTTP(bw, sr)
+
+		if sr.MultipartForm != nil {
+			_ = sr.MultipartForm.RemoveAll()
+		}
+
+		status := sm.Status(ctx)
+		if status == scs.Unmodified && state.stale {
+			status = scs.Modified
+		}
+
+		switch status {
+		case scs.Modified:
+			if _, _, err := sm.Commit(ctx); err != nil {
+				sm.ErrorFunc(w, r, err)
+				return
+			}
+
+			persist := sm.Cookie.Persist || sm.GetBool(ctx, "__rememberMe")
+			if err := writeChunks(w, r, sm, state.sealed, state.expiry, persist); err != nil {
+				sm.ErrorFunc(w, r, err)
+				return
+			}
+		case scs.Destroyed:
+			_ = writeChunks(w, r, sm, "", time.Time{}, false)
+		}
+
+		w.Header().Add("Vary", "Cookie")
+
+		if bw.code != 0 {
+			w.WriteHeader(bw.code)
+		}
+		_, _ = w.Write(bw.buf)
+	})
+}
+
+// chunkName is the name of the i-th cookie of a session; the first keeps the session's name
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return name + "_" + strconv.Itoa(i)
+}
+
+// readChunks joins the session cookies sent with r
+func readChunks(r *http.Request, name string) string {
+	var sealed string
+	for i := 0; i < maxCookieChunks; i++ {
+		cookie, err := r.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+		sealed += cookie.Value
+	}
+	return sealed
+}
+
+// writeChunks splits sealed across as many cookies as needed and expires the chunks of a
+// previous, longer session. An empty sealed value expires every chunk
+func writeChunks(w http.ResponseWriter, r *http.Request, sm *scs.SessionManager, sealed string, expiry time.Time, persist bool) error {
+	var chunks []string
+	for i := 0; len(sealed) > 0; i++ {
+		cookie := sessionCookie(sm, chunkName(sm.Cookie.Name, i), "", expiry, persist)
+		size := maxCookieSize - len(cookie.String())
+		if size > len(sealed) {
+			size = len(sealed)
+		}
+
+		chunks = append(chunks, sealed[:size])
+		sealed = sealed[size:]
+	}
+
+	if len(chunks) > maxCookieChunks {
+		return ErrCookieTooLarge
+	}
+
+	for i, chunk := range chunks {
+		cookie := sessionCookie(sm, chunkName(sm.Cookie.Name, i), chunk, expiry, persist)
+		w.Header().Add("Set-Cookie", cookie.String())
+	}
+
+	for i := len(chunks); i < maxCookieChunks; i++ {
+		name := chunkName(sm.Cookie.Name, i)
+		if _, err := r.Cookie(name); err != nil {
+			// the first chunk is always cleared, so a destroyed session is cleared
+			// even when the request did not send it
+			if i > 0 {
+				break
+			}
+		}
+		cookie := sessionCookie(sm, name, "", time.Time{}, false)
+		w.Header().Add("Set-Cookie", cookie.String())
+	}
+
+	w.Header().Add("Cache-Control", `no-cache="Set-Cookie"`)
+	return nil
+}
+
+// sessionCookie builds a cookie with the session manager's attributes. A zero expiry makes
+// the browser delete the cookie
+func sessionCookie(sm *scs.SessionManager, name, value string, expiry time.Time, persist bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     sm.Cookie.Path,
+		Domain:   sm.Cookie.Domain,
+		Secure:   sm.Cookie.Secure,
+		HttpOnly: sm.Cookie.HttpOnly,
+		SameSite: sm.Cookie.SameSite,
+	}
+
+	if expiry.IsZero() {
+		cookie.Expires = time.Unix(1, 0)
+		cookie.MaxAge = -1
+	} else if persist {
+		cookie.Expires = time.Unix(expiry.Unix()+1, 0)
+		cookie.MaxAge = int(time.Until(expiry).Seconds() + 1)
+	}
+
+	return cookie
+}
+
+// bufferedWriter holds the response until the session cookies have been written, as
+// headers cannot be added once the body has started
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf  []byte
+	code int
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	bw.buf = append(bw.buf, b...)
+	return len(b), nil
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	bw.code = code
+}
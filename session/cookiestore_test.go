@@ -0,0 +1,212 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const (
+	testKey    = "abcdefghijklmnopqrstuvwxyz123456"
+	testOldKey = "654321zyxwvutsrqponmlkjihgfedcba"
+)
+
+func newCookieSessions(keys ...string) (*scs.SessionManager, *CookieStore) {
+	store := NewCookieStore(keys...)
+	sm := scs.New()
+	sm.Store = store
+	sm.Cookie.Name = "ugo_session"
+	return sm, store
+}
+
+// cookieServer puts the value of ?put= in the session, destroys it for ?destroy=1 and
+// writes the current value to the response
+func cookieServer(sm *scs.SessionManager, store *CookieStore) http.Handler {
+	return store.LoadAndSave(sm, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.URL.Query().Get("put"); v != "" {
+			sm.Put(r.Context(), "value", v)
+		}
+		if r.URL.Query().Get("destroy") != "" {
+			_ = sm.Destroy(r.Context())
+		}
+		_, _ = w.Write([]byte(sm.GetString(r.Context(), "value")))
+	}))
+}
+
+func doCookieRequest(h http.Handler, target string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+// liveCookies returns the cookies set by a response that the browser would keep
+func liveCookies(rr *httptest.ResponseRecorder) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+func TestCookieStore_RoundTrip(t *testing.T) {
+	sm, store := newCookieSessions(testKey)
+	h := cookieServer(sm, store)
+
+	rr := doCookieRequest(h, "/?put=hello", nil)
+	cookies := liveCookies(rr)
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie; got %d", len(cookies))
+	}
+	if strings.Contains(cookies[0].Value, "hello") {
+		t.Error("session data is not encrypted")
+	}
+
+	rr = doCookieRequest(h, "/", cookies)
+	if rr.Body.String() != "hello" {
+		t.Errorf("expected hello; got %q", rr.Body.String())
+	}
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("an unmodified session should not be written again")
+	}
+}
+
+func TestCookieStore_Tampered(t *testing.T) {
+	sm, store := newCookieSessions(testKey)
+	h := cookieServer(sm, store)
+
+	cookies := liveCookies(doCookieRequest(h, "/?put=hello", nil))
+
+	value := []byte(cookies[0].Value)
+	if value[10] == 'A' {
+		value[10] = 'B'
+	} else {
+		value[10] = 'A'
+	}
+	cookies[0].Value = string(value)
+
+	rr := doCookieRequest(h, "/", cookies)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected a new session; got status %d", rr.Code)
+	}
+	if rr.Body.String() != "" {
+		t.Errorf("tampered session was accepted: %q", rr.Body.String())
+	}
+
+	_, found, err := store.Find("not a session")
+	if err != nil || found {
+		t.Errorf("expected garbage to be not found; got %v %v", found, err)
+	}
+}
+
+func TestCookieStore_Expired(t *testing.T) {
+	store := NewCookieStore(testKey)
+
+	sealed, err := store.seal([]byte("data"), time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, _ := store.Find(sealed)
+	if found {
+		t.Error("expired session should not be found")
+	}
+}
+
+func TestCookieStore_Rotation(t *testing.T) {
+	oldSM, oldStore := newCookieSessions(testOldKey)
+	cookies := liveCookies(doCookieRequest(cookieServer(oldSM, oldStore), "/?put=hello", nil))
+
+	sm, store := newCookieSessions(testKey, testOldKey)
+	h := cookieServer(sm, store)
+
+	rr := doCookieRequest(h, "/", cookies)
+	if rr.Body.String() != "hello" {
+		t.Errorf("session sealed with the previous key was not opened; got %q", rr.Body.String())
+	}
+
+	// the session is sealed again with the new key
+	resealed := liveCookies(rr)
+	if len(resealed) != 1 {
+		t.Fatalf("expected the session to be resealed; got %d cookies", len(resealed))
+	}
+
+	newSM, newStore := newCookieSessions(testKey)
+	rr = doCookieRequest(cookieServer(newSM, newStore), "/", resealed)
+	if rr.Body.String() != "hello" {
+		t.Errorf("resealed session was not opened with the new key; got %q", rr.Body.String())
+	}
+}
+
+func TestCookieStore_Chunks(t *testing.T) {
+	sm, store := newCookieSessions(testKey)
+	h := cookieServer(sm, store)
+
+	large := strings.Repeat("x", 10000)
+	rr := doCookieRequest(h, "/?put="+large, nil)
+	cookies := liveCookies(rr)
+	if len(cookies) < 3 {
+		t.Fatalf("expected the session to be split across cookies; got %d", len(cookies))
+	}
+
+	for _, header := range rr.Result().Header["Set-Cookie"] {
+		if len(header) > maxCookieSize {
+			t.Errorf("cookie is %d bytes", len(header))
+		}
+	}
+
+	rr = doCookieRequest(h, "/", cookies)
+	if rr.Body.String() != large {
+		t.Error("chunked session was not read back")
+	}
+
+	// a smaller session expires the chunks it no longer needs
+	rr = doCookieRequest(h, "/?put=small", cookies)
+	expired := 0
+	for _, c := range rr.Result().Cookies() {
+		if c.MaxAge < 0 {
+			expired++
+		}
+	}
+	if expired != len(cookies)-1 {
+		t.Errorf("expected %d chunks to be expired; got %d", len(cookies)-1, expired)
+	}
+
+	rr = doCookieRequest(h, "/?put="+strings.Repeat("x", 100000), nil)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected a session too large for cookies to fail; got %d", rr.Code)
+	}
+}
+
+func TestCookieStore_Destroy(t *testing.T) {
+	sm, store := newCookieSessions(testKey)
+	h := cookieServer(sm, store)
+
+	cookies := liveCookies(doCookieRequest(h, "/?put="+strings.Repeat("x", 5000), nil))
+
+	rr := doCookieRequest(h, "/?destroy=1", cookies)
+	live := liveCookies(rr)
+	if len(live) != 0 {
+		t.Errorf("expected no cookies after destroy; got %d", len(live))
+	}
+	if len(rr.Result().Cookies()) != len(cookies) {
+		t.Errorf("expected %d cookies to be expired; got %d", len(cookies), len(rr.Result().Cookies()))
+	}
+}
+
+func TestCookieStore_NoKey(t *testing.T) {
+	sm, store := newCookieSessions()
+	rr := doCookieRequest(cookieServer(sm, store), "/?put=hello", nil)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected a store without a key to fail; got %d", rr.Code)
+	}
+}
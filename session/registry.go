@@ -0,0 +1,460 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+var (
+	// ErrNotIndexed is returned by a Registry whose sessions are kept in cookies, as they
+	// cannot be listed or revoked on the server
+	ErrNotIndexed = errors.New("session: cookie sessions cannot be listed or revoked")
+	// ErrSessionNotFound is returned when revoking a session the user does not have
+	ErrSessionNotFound = errors.New("session: session not found")
+
+	errNoRegistryKey = errors.New("session: the registry has no key")
+)
+
+// Registry keeps an index of the sessions of each user, so a user can see where they are
+// logged in and log out their other devices. The index of a user is kept in the session
+// store itself, as one more entry, under a token derived from the user ID with Key.
+// Sessions that have ended are dropped from the index when it is next listed
+type Registry struct {
+	Manager *scs.SessionManager
+	// Key makes index tokens impossible to guess, so they cannot be sent as a session cookie
+	Key []byte
+	// UserKey is the session key holding the ID of the logged in user
+	UserKey string
+	// TouchInterval is how often the last-seen time of a session is written
+	TouchInterval time.Duration
+	// OnRevoke, when set, is called with the data of each session before it is revoked, to
+	// clean up anything that would log the device back in, such as a remember token
+	OnRevoke func(userID string, values map[string]interface{}) error
+
+	// touched holds when each session was last written to its index, so most requests
+	// neither lock nor load the index
+	touched   map[string]time.Time
+	swept     time.Time
+	touchedMu sync.Mutex
+
+	// users holds a lock for each user whose index is being changed
+	users map[string]*userLock
+	mu    sync.Mutex
+}
+
+type userLock struct {
+	sync.Mutex
+	holders int
+}
+
+// ActiveSession describes one session of a user. ID identifies the session without
+// revealing its token, so it can be shown on a page and sent back to revoke the session
+type ActiveSession struct {
+	ID        string    `json:"id"`
+	Device    string    `json:"device"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Current   bool      `json:"current"`
+}
+
+type indexEntry struct {
+	Token     string    `json:"token"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// NewRegistry returns a Registry for the sessions of sm, reading the user from "userID"
+func NewRegistry(sm *scs.SessionManager, key []byte) *Registry {
+	return &Registry{
+		Manager:       sm,
+		Key:           key,
+		UserKey:       "userID",
+		TouchInterval: time.Minute,
+	}
+}
+
+// sessionID is the public ID of a session token
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (reg *Registry) indexed() bool {
+	_, cookies := reg.Manager.Store.(*CookieStore)
+	return !cookies
+}
+
+func (reg *Registry) indexToken(userID string) (string, error) {
+	if len(reg.Key) == 0 {
+		return "", errNoRegistryKey
+	}
+
+	mac := hmac.New(sha256.New, reg.Key)
+	mac.Write([]byte("ugo session index " + userID))
+	return "index_" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (reg *Registry) load(userID string) (map[string]*indexEntry, error) {
+	token, err := reg.indexToken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]*indexEntry)
+	b, found, err := reg.Manager.Store.Find(token)
+	if err != nil || !found {
+		return index, err
+	}
+
+	// an index that cannot be read is started again rather than locking the user out
+	_ = json.Unmarshal(b, &index)
+	return index, nil
+}
+
+func (reg *Registry) save(userID string, index map[string]*indexEntry) error {
+	token, err := reg.indexToken(userID)
+	if err != nil {
+		return err
+	}
+
+	if len(index) == 0 {
+		return reg.Manager.Store.Delete(token)
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return reg.Manager.Store.Commit(token, b, time.Now().Add(reg.Manager.Lifetime))
+}
+
+// UserID returns the ID of the user logged in to the session in ctx, or "" if there is none
+func (reg *Registry) UserID(ctx context.Context) string {
+	if !reg.Manager.Exists(ctx, reg.UserKey) {
+		return ""
+	}
+	return fmt.Sprint(reg.Manager.Get(ctx, reg.UserKey))
+}
+
+// Track is middleware that adds the sessions of logged in users to the index and keeps their
+// last-seen time. It must run inside the session middleware
+func (reg *Registry) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		// after the handler, so a session is indexed by the request that logs the user in
+		if !reg.indexed() {
+			return
+		}
+
+		ctx := r.Context()
+		userID := reg.UserID(ctx)
+		if userID == "" {
+			return
+		}
+
+		// a new session only gets a token when it is saved, and the index needs it now
+		if reg.Manager.Token(ctx) == "" {
+			if err := reg.Manager.RenewToken(ctx); err != nil {
+				return
+			}
+		}
+
+		_ = reg.touch(userID, reg.Manager.Token(ctx), r)
+	})
+}
+
+// touch adds the session to the index of userID, or updates its last-seen time once
+// TouchInterval has passed
+func (reg *Registry) touch(userID, token string, r *http.Request) error {
+	now := time.Now()
+	id := sessionID(token)
+	key := userID + "\x00" + id
+
+	if reg.recentlyTouched(key, now) {
+		return nil
+	}
+
+	defer reg.lock(userID)()
+
+	index, err := reg.load(userID)
+	if err != nil {
+		return err
+	}
+
+	// another request, or another instance of the application, may have written it already
+	entry, ok := index[id]
+	if ok && now.Sub(entry.LastSeen) < reg.TouchInterval {
+		reg.markTouched(key, entry.LastSeen)
+		return nil
+	}
+
+	if !ok {
+		entry = &indexEntry{Token: token, CreatedAt: now}
+		index[id] = entry
+	}
+	entry.UserAgent = r.UserAgent()
+	entry.IP = clientIP(r)
+	entry.LastSeen = now
+
+	if err := reg.save(userID, index); err != nil {
+		return err
+	}
+
+	reg.markTouched(key, now)
+	return nil
+}
+
+func (reg *Registry) recentlyTouched(key string, now time.Time) bool {
+	reg.touchedMu.Lock()
+	defer reg.touchedMu.Unlock()
+
+	last, ok := reg.touched[key]
+	return ok && now.Sub(last) < reg.TouchInterval
+}
+
+func (reg *Registry) markTouched(key string, at time.Time) {
+	reg.touchedMu.Lock()
+	defer reg.touchedMu.Unlock()
+
+	if reg.touched == nil {
+		reg.touched = make(map[string]time.Time)
+	}
+	reg.touched[key] = at
+
+	// sessions that stopped making requests are forgotten once they are due a write anyway
+	now := time.Now()
+	if now.Sub(reg.swept) >= reg.TouchInterval {
+		for k, last := range reg.touched {
+			if now.Sub(last) >= reg.TouchInterval {
+				delete(reg.touched, k)
+			}
+		}
+		reg.swept = now
+	}
+}
+
+// lock locks the index of userID, so the requests of other users do not wait on it, and
+// returns the function that unlocks it
+func (reg *Registry) lock(userID string) func() {
+	reg.mu.Lock()
+	if reg.users == nil {
+		reg.users = make(map[string]*userLock)
+	}
+	l, ok := reg.users[userID]
+	if !ok {
+		l = &userLock{}
+		reg.users[userID] = l
+	}
+	l.holders++
+	reg.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		reg.mu.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(reg.users, userID)
+		}
+		reg.mu.Unlock()
+	}
+}
+
+// List returns the live sessions of userID, most recently used first. The session in ctx,
+// if it is one of them, is marked Current
+func (reg *Registry) List(ctx context.Context, userID string) ([]ActiveSession, error) {
+	if !reg.indexed() {
+		return nil, ErrNotIndexed
+	}
+
+	defer reg.lock(userID)()
+
+	index, err := reg.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := reg.Manager.Token(ctx)
+	var sessions []ActiveSession
+	pruned := false
+
+	for id, entry := range index {
+		_, found, err := reg.Manager.Store.Find(entry.Token)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			delete(index, id)
+			pruned = true
+			continue
+		}
+
+		sessions = append(sessions, ActiveSession{
+			ID:        id,
+			Device:    Device(entry.UserAgent),
+			UserAgent: entry.UserAgent,
+			IP:        entry.IP,
+			CreatedAt: entry.CreatedAt,
+			LastSeen:  entry.LastSeen,
+			Current:   entry.Token == current,
+		})
+	}
+
+	if pruned {
+		if err := reg.save(userID, index); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// Revoke ends the session of userID with the given ID. The user is logged out of that
+// device on its next request
+func (reg *Registry) Revoke(userID, id string) error {
+	if !reg.indexed() {
+		return ErrNotIndexed
+	}
+
+	defer reg.lock(userID)()
+
+	index, err := reg.load(userID)
+	if err != nil {
+		return err
+	}
+
+	entry, ok := index[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if err := reg.revoke(userID, entry.Token); err != nil {
+		return err
+	}
+
+	delete(index, id)
+	return reg.save(userID, index)
+}
+
+// RevokeOthers ends every session of userID except the one in ctx
+func (reg *Registry) RevokeOthers(ctx context.Context, userID string) error {
+	if !reg.indexed() {
+		return ErrNotIndexed
+	}
+
+	defer reg.lock(userID)()
+
+	index, err := reg.load(userID)
+	if err != nil {
+		return err
+	}
+
+	current := reg.Manager.Token(ctx)
+	for id, entry := range index {
+		if entry.Token == current {
+			continue
+		}
+
+		if err := reg.revoke(userID, entry.Token); err != nil {
+			return err
+		}
+		delete(index, id)
+	}
+
+	return reg.save(userID, index)
+}
+
+// revoke deletes a session, after handing its data to OnRevoke
+func (reg *Registry) revoke(userID, token string) error {
+	if reg.OnRevoke != nil {
+		b, found, err := reg.Manager.Store.Find(token)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			_, values, err := reg.Manager.Codec.Decode(b)
+			if err != nil {
+				return err
+			}
+
+			if err := reg.OnRevoke(userID, values); err != nil {
+				return err
+			}
+		}
+	}
+
+	return reg.Manager.Store.Delete(token)
+}
+
+// clientIP returns the address of the client without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Device describes a user agent as "Browser on OS", for listing sessions
+func Device(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	for _, b := range [][2]string{
+		// order matters: Edge and Opera also announce Chrome, and Chrome announces Safari
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b[0]) {
+			browser = b[1]
+			break
+		}
+	}
+
+	os := "unknown OS"
+	for _, o := range [][2]string{
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o[0]) {
+			os = o[1]
+			break
+		}
+	}
+
+	return browser + " on " + os
+}
@@ -0,0 +1,263 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// registryServer logs in user ?login= and lists the sessions of the user in /list
+func registryServer(sm *scs.SessionManager, reg *Registry) (http.Handler, *[]ActiveSession) {
+	var listed []ActiveSession
+
+	h := sm.LoadAndSave(reg.Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.URL.Query().Get("login"); id != "" {
+			sm.Put(r.Context(), "userID", id)
+		}
+
+		if r.URL.Path == "/list" {
+			sessions, err := reg.List(r.Context(), reg.UserID(r.Context()))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			listed = sessions
+		}
+
+		if r.URL.Path == "/revoke-others" {
+			if err := reg.RevokeOthers(r.Context(), reg.UserID(r.Context())); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	})))
+
+	return h, &listed
+}
+
+func login(t *testing.T, h http.Handler, userID, userAgent string) *http.Cookie {
+	req := httptest.NewRequest("GET", "/?login="+userID, nil)
+	req.Header.Set("User-Agent", userAgent)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "session" {
+			return c
+		}
+	}
+	t.Fatal("no session cookie")
+	return nil
+}
+
+func get(h http.Handler, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRegistry(t *testing.T) {
+	sm := scs.New()
+	reg := NewRegistry(sm, []byte(testKey))
+	h, listed := registryServer(sm, reg)
+
+	firefox := "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1"
+
+	desktop := login(t, h, "1", firefox)
+	phone := login(t, h, "1", iphone)
+	other := login(t, h, "2", firefox)
+
+	if rr := get(h, "/list", desktop); rr.Code != http.StatusOK {
+		t.Fatal(rr.Body.String())
+	}
+	if len(*listed) != 2 {
+		t.Fatalf("expected 2 sessions for user 1; got %d", len(*listed))
+	}
+
+	var current, remote ActiveSession
+	for _, s := range *listed {
+		if s.Current {
+			current = s
+		} else {
+			remote = s
+		}
+	}
+	if current.Device != "Firefox on Linux" || remote.Device != "Safari on iOS" {
+		t.Errorf("wrong devices: %q %q", current.Device, remote.Device)
+	}
+	if current.IP != "192.0.2.1" || current.LastSeen.IsZero() {
+		t.Errorf("expected the IP and last-seen time; got %+v", current)
+	}
+
+	// revoking the phone logs it out
+	if err := reg.Revoke("1", remote.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Revoke("1", remote.ID); err != ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound; got %v", err)
+	}
+
+	get(h, "/list", phone)
+	if len(*listed) != 0 {
+		t.Error("revoked session is still logged in")
+	}
+
+	login(t, h, "1", iphone)
+	get(h, "/revoke-others", desktop)
+	get(h, "/list", desktop)
+	if len(*listed) != 1 || !(*listed)[0].Current {
+		t.Errorf("expected only the current session to be left; got %+v", *listed)
+	}
+
+	// other users are not touched
+	get(h, "/list", other)
+	if len(*listed) != 1 {
+		t.Errorf("expected 1 session for user 2; got %d", len(*listed))
+	}
+}
+
+func TestRegistry_Prune(t *testing.T) {
+	sm := scs.New()
+	reg := NewRegistry(sm, []byte(testKey))
+	h, listed := registryServer(sm, reg)
+
+	first := login(t, h, "1", "")
+	second := login(t, h, "1", "")
+
+	// a session that ends without being revoked is dropped when the index is listed
+	if err := sm.Store.Delete(second.Value); err != nil {
+		t.Fatal(err)
+	}
+
+	get(h, "/list", first)
+	if len(*listed) != 1 {
+		t.Errorf("expected the ended session to be pruned; got %d sessions", len(*listed))
+	}
+}
+
+// countingStore counts how often the indexes of users are read
+type countingStore struct {
+	scs.Store
+	indexReads int32
+}
+
+func (s *countingStore) Find(token string) ([]byte, bool, error) {
+	if strings.HasPrefix(token, "index_") {
+		atomic.AddInt32(&s.indexReads, 1)
+	}
+	return s.Store.Find(token)
+}
+
+func TestRegistry_TouchInterval(t *testing.T) {
+	sm := scs.New()
+	store := &countingStore{Store: sm.Store}
+	sm.Store = store
+	reg := NewRegistry(sm, []byte(testKey))
+	h, _ := registryServer(sm, reg)
+
+	cookie := login(t, h, "1", "")
+	reads := atomic.LoadInt32(&store.indexReads)
+
+	// within TouchInterval, requests neither read nor write the index
+	for i := 0; i < 5; i++ {
+		get(h, "/", cookie)
+	}
+	if got := atomic.LoadInt32(&store.indexReads); got != reads {
+		t.Errorf("expected no index reads within TouchInterval; got %d", got-reads)
+	}
+
+	// once it has passed, the last-seen time is written again
+	reg.TouchInterval = 10 * time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+	get(h, "/", cookie)
+	if got := atomic.LoadInt32(&store.indexReads); got != reads+1 {
+		t.Errorf("expected 1 index read after TouchInterval; got %d", got-reads)
+	}
+}
+
+func TestRegistry_ConcurrentLogins(t *testing.T) {
+	sm := scs.New()
+	reg := NewRegistry(sm, []byte(testKey))
+	h, listed := registryServer(sm, reg)
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = httptest.NewRecorder()
+			h.ServeHTTP(responses[i], httptest.NewRequest("GET", fmt.Sprintf("/?login=%d", i%2), nil))
+		}(i)
+	}
+	wg.Wait()
+
+	// logins of the same user wait for each other, so no session is lost from the index
+	get(h, "/list", responses[0].Result().Cookies()[0])
+	if len(*listed) != 5 {
+		t.Errorf("expected 5 sessions for user 0; got %d", len(*listed))
+	}
+	if len(reg.users) != 0 {
+		t.Errorf("expected the user locks to be released; got %d", len(reg.users))
+	}
+}
+
+func TestRegistry_CookieStore(t *testing.T) {
+	sm, _ := newCookieSessions(testKey)
+	reg := NewRegistry(sm, []byte(testKey))
+
+	if _, err := reg.List(context.Background(), "1"); err != ErrNotIndexed {
+		t.Errorf("expected ErrNotIndexed; got %v", err)
+	}
+}
+
+func TestDevice(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0": "Edge on Windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36":         "Chrome on macOS",
+		"curl/8.0.1": "curl on unknown OS",
+		"":           "Unknown browser on unknown OS",
+	}
+
+	for ua, want := range tests {
+		if got := Device(ua); got != want {
+			t.Errorf("Device(%q) = %q; want %q", ua, got, want)
+		}
+	}
+}
+
+func TestRegistry_OnRevoke(t *testing.T) {
+	sm := scs.New()
+	reg := NewRegistry(sm, []byte(testKey))
+	h, listed := registryServer(sm, reg)
+
+	var revoked []string
+	reg.OnRevoke = func(userID string, values map[string]interface{}) error {
+		revoked = append(revoked, userID+":"+values["userID"].(string))
+		return nil
+	}
+
+	first := login(t, h, "1", "")
+	login(t, h, "1", "")
+
+	get(h, "/revoke-others", first)
+	if len(revoked) != 1 || revoked[0] != "1:1" {
+		t.Errorf("expected OnRevoke to get the revoked session; got %v", revoked)
+	}
+
+	get(h, "/list", first)
+	if len(*listed) != 1 {
+		t.Errorf("expected 1 session left; got %d", len(*listed))
+	}
+}
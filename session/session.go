@@ -2,6 +2,7 @@ package session
 
 import (
 	"database/sql"
+	"github.com/dgraph-io/badger/v3"
 	"github.com/gomodule/redigo/redis"
 	"net/http"
 	"strconv"
@@ -21,8 +22,14 @@ type Session struct {
 	CookieDomain   string
 	SessionType    string
 	CookieSecure   string
+	CookieSameSite string
+	IdleTimeout    string // minutes; empty or 0 never ends an idle session
 	DBPool         *sql.DB
 	RedisPool      *redis.Pool
+	BadgerConn     *badger.DB
+	// EncryptionKeys seal cookie sessions. The first seals new sessions; the rest only open
+	// sessions sealed before a key rotation
+	EncryptionKeys []string
 }
 
 func (s *Session) InitSession() *scs.SessionManager {
@@ -49,19 +56,43 @@ func (s *Session) InitSession() *scs.SessionManager {
 	session.Cookie.Name = s.CookieName
 	session.Cookie.Secure = secure
 	session.Cookie.Domain = s.CookieDomain
-	session.Cookie.SameSite = http.SameSiteLaxMode
+	session.Cookie.SameSite = sameSite(s.CookieSameSite)
+
+	// CookieLifetime is also the absolute timeout, as scs only extends a session's deadline when
+	// its token is renewed, and ugo's RenewSession keeps the deadline it had
+	if idle, err := strconv.Atoi(s.IdleTimeout); err == nil && idle > 0 {
+		session.IdleTimeout = time.Duration(idle) * time.Minute
+	}
 
 	// which session store
 	switch strings.ToLower(s.SessionType) {
+	case "cookie":
+		session.Store = NewCookieStore(s.EncryptionKeys...)
 	case "redis":
 		session.Store = redisstore.New(s.RedisPool)
+	case "badger":
+		session.Store = NewBadgerStore(s.BadgerConn)
 	case "mysql", "mariadb":
 		session.Store = mysqlstore.New(s.DBPool)
-	case "postgres", "postgressql":
+	case "postgres", "postgresql", "postgressql":
 		session.Store = postgresstore.New(s.DBPool)
+	case "sqlite":
+		session.Store = NewSQLiteStore(s.DBPool)
 	default:
 
 	}
 
 	return session
 }
+
+// sameSite converts lax, strict or none to a SameSite mode. Lax is the default
+func sameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
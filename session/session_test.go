@@ -2,8 +2,10 @@ package session
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 )
@@ -48,3 +50,37 @@ func TestSession_InitSession(t *testing.T) {
 	}
 
 }
+
+func TestSession_InitSession_Policies(t *testing.T) {
+
+	s := &Session{
+		CookieLifetime: "120",
+		CookieName:     "ugo",
+		CookieSameSite: "Strict",
+		IdleTimeout:    "15",
+		SessionType:    "cookie",
+	}
+
+	sm := s.InitSession()
+
+	if sm.Lifetime != 120*time.Minute {
+		t.Errorf("expected a lifetime of 2h; got %s", sm.Lifetime)
+	}
+	if sm.IdleTimeout != 15*time.Minute {
+		t.Errorf("expected an idle timeout of 15m; got %s", sm.IdleTimeout)
+	}
+	if sm.Cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected SameSite strict; got %v", sm.Cookie.SameSite)
+	}
+
+	s.CookieSameSite = ""
+	s.IdleTimeout = ""
+	sm = s.InitSession()
+
+	if sm.IdleTimeout != 0 {
+		t.Errorf("expected no idle timeout; got %s", sm.IdleTimeout)
+	}
+	if sm.Cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expected SameSite lax by default; got %v", sm.Cookie.SameSite)
+	}
+}
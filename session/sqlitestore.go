@@ -0,0 +1,104 @@
+package session
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// SQLiteStore is an scs.Store backed by a SQLite sessions table. Expiry is stored as a unix
+// timestamp so it does not depend on how the driver formats time.Time values
+type SQLiteStore struct {
+	db          *sql.DB
+	stopCleanup chan bool
+}
+
+// NewSQLiteStore returns a SQLiteStore that deletes expired sessions every 5 minutes
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return NewSQLiteStoreWithCleanupInterval(db, 5*time.Minute)
+}
+
+// NewSQLiteStoreWithCleanupInterval returns a SQLiteStore that deletes expired sessions at the given
+// interval. An interval of zero disables the cleanup
+func NewSQLiteStoreWithCleanupInterval(db *sql.DB, cleanupInterval time.Duration) *SQLiteStore {
+	s := &SQLiteStore{db: db}
+	if cleanupInterval > 0 {
+		s.stopCleanup = make(chan bool)
+		go s.startCleanup(cleanupInterval, s.stopCleanup)
+	}
+	return s
+}
+
+// Find returns the data for a session token. exists is false if the token is unknown or expired
+func (s *SQLiteStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+	row := s.db.QueryRow("SELECT data FROM sessions WHERE token = ? AND expiry > ?", token, time.Now().Unix())
+	err := row.Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Commit adds or replaces the data for a session token
+func (s *SQLiteStore) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := s.db.Exec("REPLACE INTO sessions (token, data, expiry) VALUES (?, ?, ?)", token, b, expiry.Unix())
+	return err
+}
+
+// Delete removes a session token and its data
+func (s *SQLiteStore) Delete(token string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE token = ?", token)
+	return err
+}
+
+// All returns the data of every session that has not expired, keyed by token
+func (s *SQLiteStore) All() (map[string][]byte, error) {
+	rows, err := s.db.Query("SELECT token, data FROM sessions WHERE expiry > ?", time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make(map[string][]byte)
+	for rows.Next() {
+		var token string
+		var data []byte
+		if err := rows.Scan(&token, &data); err != nil {
+			return nil, err
+		}
+		sessions[token] = data
+	}
+
+	return sessions, rows.Err()
+}
+
+// StopCleanup terminates the background cleanup goroutine. Calling it again does nothing
+func (s *SQLiteStore) StopCleanup() {
+	if s.stopCleanup != nil {
+		s.stopCleanup <- true
+		s.stopCleanup = nil
+	}
+}
+
+func (s *SQLiteStore) startCleanup(interval time.Duration, stop <-chan bool) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.deleteExpired(); err != nil {
+				log.Println(err)
+			}
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (s *SQLiteStore) deleteExpired() error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE expiry <= ?", time.Now().Unix())
+	return err
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteStore(t *testing.T) {
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE sessions (token TEXT PRIMARY KEY, data BLOB NOT NULL, expiry INTEGER NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSQLiteStoreWithCleanupInterval(db, 0)
+
+	err = store.Commit("foo", []byte("bar"), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	b, found, err := store.Find("foo")
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || string(b) != "bar" {
+		t.Errorf("expected to find bar for foo; got %q %v", b, found)
+	}
+
+	err = store.Commit("expired", []byte("bar"), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, found, err = store.Find("expired")
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("expired session should not be found")
+	}
+
+	all, err := store.All()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("expected 1 live session; got %d", len(all))
+	}
+
+	err = store.Delete("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, found, _ = store.Find("foo")
+	if found {
+		t.Error("foo should have been deleted")
+	}
+}
+
+func TestSQLiteStore_StopCleanup(t *testing.T) {
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE sessions (token TEXT PRIMARY KEY, data BLOB NOT NULL, expiry INTEGER NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSQLiteStoreWithCleanupInterval(db, 10*time.Millisecond)
+	_ = store.Commit("expired", []byte("bar"), time.Now().Add(-time.Minute))
+
+	time.Sleep(50 * time.Millisecond)
+
+	var n int
+	_ = db.QueryRow("SELECT count(*) FROM sessions").Scan(&n)
+	if n != 0 {
+		t.Errorf("expected the cleanup to delete the expired session; got %d sessions", n)
+	}
+
+	// a second call must not block on a cleanup that has already stopped
+	done := make(chan struct{})
+	go func() {
+		store.StopCleanup()
+		store.StopCleanup()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StopCleanup blocked")
+	}
+}
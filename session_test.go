@@ -0,0 +1,93 @@
+package ugo
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/joefazee/ugo/session"
+)
+
+// sessionServer logs in on /login, renews the session on /renew and writes the logged in
+// user otherwise
+func sessionServer(u *Ugo) http.Handler {
+	return u.SessionLoad(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/login":
+			err = u.LoginSession(r.Context(), "1")
+		case "/renew":
+			err = u.RenewSession(r.Context())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, u.Sessions.UserID(r.Context()))
+	}))
+}
+
+func sessionRequest(h http.Handler, path string, cookie *http.Cookie) (string, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	return rr.Body.String(), cookie
+}
+
+func TestRenewSession_KeepsDeadline(t *testing.T) {
+	sm := scs.New()
+	sm.Lifetime = 300 * time.Millisecond
+
+	u := &Ugo{Session: sm, Sessions: session.NewRegistry(sm, []byte(testKey)), ErrorLog: log.New(os.Stderr, "", 0)}
+	h := sessionServer(u)
+
+	_, cookie := sessionRequest(h, "/login", nil)
+
+	time.Sleep(150 * time.Millisecond)
+	if user, renewed := sessionRequest(h, "/renew", cookie); user != "1" || renewed.Value == cookie.Value {
+		t.Fatalf("expected a new token for user 1; got %q", user)
+	} else {
+		cookie = renewed
+	}
+
+	// the new token would live another 300ms, but the session started 300ms ago
+	time.Sleep(200 * time.Millisecond)
+	if user, _ := sessionRequest(h, "/", cookie); user != "" {
+		t.Errorf("expected the renewed session to end at its original deadline; got user %q", user)
+	}
+}
+
+func TestLoginSession_FullLifetime(t *testing.T) {
+	sm := scs.New()
+	sm.Lifetime = 300 * time.Millisecond
+
+	u := &Ugo{Session: sm, Sessions: session.NewRegistry(sm, []byte(testKey)), ErrorLog: log.New(os.Stderr, "", 0)}
+	h := sessionServer(u)
+
+	_, cookie := sessionRequest(h, "/login", nil)
+	_, cookie = sessionRequest(h, "/renew", cookie)
+
+	// logging in again starts a new session, which is not bound by the renewed deadline
+	time.Sleep(150 * time.Millisecond)
+	_, cookie = sessionRequest(h, "/login", cookie)
+
+	time.Sleep(200 * time.Millisecond)
+	if user, _ := sessionRequest(h, "/", cookie); user != "1" {
+		t.Errorf("expected the new login to last a full lifetime; got user %q", user)
+	}
+}
@@ -0,0 +1,94 @@
+package ugo
+
+import (
+	"context"
+	"net/http"
+)
+
+// OnStart registers a function to run before the web server starts listening.
+// If any hook returns an error the server is not started
+func (u *Ugo) OnStart(fn func() error) {
+	u.onStart = append(u.onStart, fn)
+}
+
+// start runs the OnStart hooks in order of registration, and stops at the first that fails
+func (u *Ugo) start() error {
+	for _, fn := range u.onStart {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnShutdown registers a function to run during graceful shutdown, after the web server,
+// scheduler and mailer have stopped but before database and cache connections are closed.
+// Hooks run in reverse order of registration
+func (u *Ugo) OnShutdown(fn func(ctx context.Context) error) {
+	u.onShutdown = append(u.onShutdown, fn)
+}
+
+// shutdown stops everything the application started, in order: the web servers, the scheduler,
+// the mail listener, the registered shutdown hooks, the session store's cleanup and finally the
+// database, redis and badger connections. Failures are logged and do not stop the remaining steps
+func (u *Ugo) shutdown(ctx context.Context, servers ...*http.Server) {
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			u.ErrorLog.Println("error shutting down web server:", err)
+		}
+	}
+
+	if u.Scheduler != nil {
+		select {
+		case <-u.Scheduler.Stop().Done():
+		case <-ctx.Done():
+			u.ErrorLog.Println("error stopping scheduler:", ctx.Err())
+		}
+	}
+
+	if u.Mail.Jobs != nil {
+		if err := u.Mail.Shutdown(ctx); err != nil {
+			u.ErrorLog.Println("error draining mail queue:", err)
+		}
+	}
+
+	for i := len(u.onShutdown) - 1; i >= 0; i-- {
+		if err := u.onShutdown[i](ctx); err != nil {
+			u.ErrorLog.Println("error running shutdown hook:", err)
+		}
+	}
+
+	// the mysql, postgres and sqlite session stores delete expired sessions in the background,
+	// which must stop before the database is closed
+	if u.Session != nil {
+		if store, ok := u.Session.Store.(interface{ StopCleanup() }); ok {
+			store.StopCleanup()
+		}
+	}
+
+	if u.DB.Pool != nil {
+		if err := u.DB.Close(); err != nil {
+			u.ErrorLog.Println("error closing database:", err)
+		}
+	}
+
+	if redisPool != nil {
+		if err := redisPool.Close(); err != nil {
+			u.ErrorLog.Println("error closing redis pool:", err)
+		}
+	}
+
+	if badgerConn != nil {
+		if err := badgerConn.Close(); err != nil {
+			u.ErrorLog.Println("error closing badger:", err)
+		}
+	}
+
+	if sessionBadgerConn != nil {
+		if err := sessionBadgerConn.Close(); err != nil {
+			u.ErrorLog.Println("error closing badger session store:", err)
+		}
+	}
+
+	u.InfoLog.Println("Shutdown complete")
+}
@@ -0,0 +1,158 @@
+package ugo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// shutdownApp returns an application serving h on a local port, and the server to shut down
+func shutdownApp(t *testing.T, h http.Handler) (*Ugo, *http.Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: h}
+	go func() { _ = srv.Serve(ln) }()
+
+	u := &Ugo{
+		ErrorLog:  log.New(io.Discard, "", 0),
+		InfoLog:   log.New(io.Discard, "", 0),
+		Scheduler: cron.New(),
+	}
+	u.Scheduler.Start()
+
+	return u, srv, "http://" + ln.Addr().String()
+}
+
+func TestStart_HookOrder(t *testing.T) {
+	u := &Ugo{}
+	var order []string
+	hook := func(name string, err error) func() error {
+		return func() error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	u.OnStart(hook("first", nil))
+	u.OnStart(hook("second", errors.New("cannot start")))
+	u.OnStart(hook("third", nil))
+
+	if err := u.start(); err == nil || err.Error() != "cannot start" {
+		t.Errorf("expected the error of the failing hook; got %v", err)
+	}
+	if expected := []string{"first", "second"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected hooks %v; got %v", expected, order)
+	}
+}
+
+func TestShutdown_DrainsRequests(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(step string) {
+		mu.Lock()
+		order = append(order, step)
+		mu.Unlock()
+	}
+
+	started := make(chan struct{})
+	u, srv, url := shutdownApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		record("request")
+		_, _ = io.WriteString(w, "done")
+	}))
+
+	for _, name := range []string{"first", "second"} {
+		name := name
+		u.OnShutdown(func(ctx context.Context) error {
+			record(name)
+			return nil
+		})
+	}
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	u.shutdown(ctx, srv)
+
+	if b := <-body; b != "done" {
+		t.Errorf("expected the request in flight to be answered; got %q", b)
+	}
+
+	// the request finishes before the hooks run, and the hooks run in reverse order
+	if expected := []string{"request", "second", "first"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected %v; got %v", expected, order)
+	}
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	u, srv, url := shutdownApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer srv.Close()
+
+	var logged bytes.Buffer
+	u.ErrorLog = log.New(&logged, "", 0)
+
+	var hookErr error
+	u.OnShutdown(func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		return nil
+	})
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	u.shutdown(ctx, srv)
+
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("expected shutdown to give up after the timeout; took %s", elapsed)
+	}
+	if !strings.Contains(logged.String(), "error shutting down web server") {
+		t.Errorf("expected the stuck request to be logged; got %q", logged.String())
+	}
+
+	// the remaining steps still run, with the expired context
+	if hookErr != context.DeadlineExceeded {
+		t.Errorf("expected the hooks to run after the timeout; got %v", hookErr)
+	}
+}
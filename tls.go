@@ -0,0 +1,151 @@
+package ugo
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// how often the certificate files are checked for changes
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves a certificate loaded from disk and reloads it whenever the
+// certificate or key file changes, so renewed certificates are picked up without a restart
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	errorLog  *log.Logger
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile, keyFile string, errorLog *log.Logger) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		errorLog: errorLog,
+	}
+
+	if err := cr.load(); err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
+func (cr *certReloader) load() error {
+	modTime, err := cr.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.modTime = modTime
+	cr.lastCheck = time.Now()
+	cr.mu.Unlock()
+
+	return nil
+}
+
+func (cr *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{cr.certFile, cr.keyFile} {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (cr *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	cert, modTime, stale := cr.cert, cr.modTime, time.Since(cr.lastCheck) > certCheckInterval
+	cr.mu.RUnlock()
+
+	if !stale {
+		return cert, nil
+	}
+
+	cr.mu.Lock()
+	cr.lastCheck = time.Now()
+	cr.mu.Unlock()
+
+	latest, err := cr.latestModTime()
+	if err != nil || !latest.After(modTime) {
+		return cert, nil
+	}
+
+	// keep serving the old certificate if the new pair is incomplete or invalid
+	if err := cr.load(); err != nil {
+		cr.errorLog.Println("error reloading tls certificate:", err)
+		return cert, nil
+	}
+
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// tlsEnabled reports whether both a certificate and a key have been configured
+func (u *Ugo) tlsEnabled() bool {
+	return u.Config.Server.TLSCert != "" && u.Config.Server.TLSKey != ""
+}
+
+// configureTLS sets up the server to serve HTTPS and HTTP/2 using the configured certificate
+func (u *Ugo) configureTLS(srv *http.Server) error {
+	reloader, err := newCertReloader(u.Config.Server.TLSCert, u.Config.Server.TLSKey, u.ErrorLog)
+	if err != nil {
+		return err
+	}
+
+	srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	return nil
+}
+
+// redirectServer returns a server that redirects every plain HTTP request to HTTPS
+func (u *Ugo) redirectServer() *http.Server {
+	return &http.Server{
+		Addr:         net.JoinHostPort(u.Server.Name, u.Config.Server.TLSRedirectPort),
+		ErrorLog:     u.ErrorLog,
+		Handler:      http.HandlerFunc(u.redirectToHTTPS),
+		IdleTimeout:  30 * time.Second,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+}
+
+func (u *Ugo) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	if u.Config.Server.Port != "" && u.Config.Server.Port != "443" {
+		host = net.JoinHostPort(host, u.Config.Server.Port)
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
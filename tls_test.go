@@ -0,0 +1,177 @@
+package ugo
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate for name and its key, dated modTime
+func writeTestCert(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for file, block := range files {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// certName returns the name a certificate was issued for
+func certName(t *testing.T, cert *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader_GetCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	now := time.Now()
+	writeTestCert(t, certFile, keyFile, "first.example.com", now.Add(-time.Hour))
+
+	var logged bytes.Buffer
+	cr, err := newCertReloader(certFile, keyFile, log.New(&logged, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expire the last check, as GetCertificate looks at the files every certCheckInterval
+	check := func() string {
+		cr.mu.Lock()
+		cr.lastCheck = time.Now().Add(-2 * certCheckInterval)
+		cr.mu.Unlock()
+
+		cert, err := cr.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return certName(t, cert)
+	}
+
+	if name := check(); name != "first.example.com" {
+		t.Errorf("expected the first certificate; got %s", name)
+	}
+
+	// a renewed pair is served once the files are checked again
+	writeTestCert(t, certFile, keyFile, "second.example.com", now)
+	cert, _ := cr.GetCertificate(nil)
+	if name := certName(t, cert); name != "first.example.com" {
+		t.Errorf("expected the files to be checked only every %s; got %s", certCheckInterval, name)
+	}
+	if name := check(); name != "second.example.com" {
+		t.Errorf("expected the renewed certificate; got %s", name)
+	}
+
+	// a key that does not match is logged, and the last good certificate kept
+	writeTestCert(t, certFile, filepath.Join(dir, "other-key.pem"), "third.example.com", now.Add(time.Minute))
+	if name := check(); name != "second.example.com" {
+		t.Errorf("expected the last good certificate; got %s", name)
+	}
+	if !strings.Contains(logged.String(), "error reloading tls certificate") {
+		t.Errorf("expected the failed reload to be logged; got %q", logged.String())
+	}
+}
+
+func TestConfigureTLS(t *testing.T) {
+	dir := t.TempDir()
+	u := &Ugo{ErrorLog: log.New(io.Discard, "", 0)}
+	u.Config.Server.TLSCert, u.Config.Server.TLSKey = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	srv := &http.Server{}
+	if err := u.configureTLS(srv); err == nil {
+		t.Error("expected an error without certificate files")
+	}
+
+	writeTestCert(t, u.Config.Server.TLSCert, u.Config.Server.TLSKey, "app.example.com", time.Now())
+	if err := u.configureTLS(srv); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.TLSConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2 at least; got %x", srv.TLSConfig.MinVersion)
+	}
+	if len(srv.TLSConfig.NextProtos) == 0 || srv.TLSConfig.NextProtos[0] != "h2" {
+		t.Errorf("expected HTTP/2 to be offered; got %v", srv.TLSConfig.NextProtos)
+	}
+
+	cert, err := srv.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := certName(t, cert); name != "app.example.com" {
+		t.Errorf("expected the configured certificate; got %s", name)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name, port, target, location string
+	}{
+		{"https_port", "4443", "http://example.com:8080/users?page=2", "https://example.com:4443/users?page=2"},
+		{"default_port", "443", "http://example.com/login", "https://example.com/login"},
+		{"no_port", "", "http://example.com:8080/", "https://example.com/"},
+		{"ipv6", "4443", "http://[::1]:8080/a%20b", "https://[::1]:4443/a%20b"},
+	}
+
+	for _, tt := range tests {
+		u := &Ugo{}
+		u.Config.Server.Port = tt.port
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+		u.redirectToHTTPS(rr, req)
+
+		// 308 keeps the method and body of the request
+		if rr.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected status %d; got %d", tt.name, http.StatusPermanentRedirect, rr.Code)
+		}
+		if location := rr.Header().Get("Location"); location != tt.location {
+			t.Errorf("%s: expected %s; got %s", tt.name, tt.location, location)
+		}
+	}
+}
@@ -9,26 +9,15 @@ type initPaths struct {
 	folderNames []string
 }
 
-type cookieConfig struct {
-	name     string
-	lifetime string
-	persist  string
-	secure   string
-	domain   string
-}
-
-type databaseConfig struct {
-	dsn      string
-	database string
-}
-
 type database struct {
 	DataType string
 	Pool     *sql.DB
+	replicas []*replica
+	next     uint32
+	stop     chan struct{}
 }
 
-type redisConfig struct {
-	host     string
-	password string
-	prefix   string
+type replica struct {
+	pool    *sql.DB
+	healthy int32
 }
@@ -1,35 +1,41 @@
 package ugo
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/gomodule/redigo/redis"
 	"github.com/joefazee/ugo/cache"
+	"github.com/joefazee/ugo/i18n"
 	"github.com/joefazee/ugo/mailer"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/CloudyKit/jet/v6"
+	"github.com/CloudyKit/jet/v6/loaders/httpfs"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-sql-driver/mysql"
 	"github.com/joefazee/ugo/render"
 	"github.com/joefazee/ugo/session"
-	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
 )
 
 const (
-	alphNum  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890_+"
-	version  = "1.0.0"
-	envFile  = ".env"
-	debug    = "DEBUG"
-	port     = "PORT"
-	renderer = "RENDERER"
+	alphNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890_+"
+	version = "1.0.0"
+	envFile = ".env"
 )
 
 var (
@@ -37,6 +43,8 @@ var (
 	badgerCache *cache.BadgerCache
 	redisPool   *redis.Pool
 	badgerConn  *badger.DB
+	// sessions get their own badger database, as emptying the badger cache clears every key
+	sessionBadgerConn *badger.DB
 )
 
 type (
@@ -48,16 +56,22 @@ type (
 		InfoLog       *log.Logger
 		RootPath      string
 		Routes        *chi.Mux
-		config        config
+		Config        Config
 		Render        *render.Render
 		JetViews      *jet.Set
 		Session       *scs.SessionManager
+		Sessions      *session.Registry
+		I18n          *i18n.Bundle
 		DB            database
 		EncryptionKey string
+		FS            fs.FS // when set before New, views, mail, lang and public are read from it, e.g. an embed.FS
 		Cache         cache.Cache
+		cacheMetrics  *cache.InstrumentedCache
 		Scheduler     *cron.Cron
 		Mail          mailer.Mail
 		Server        Server
+		onStart       []func() error
+		onShutdown    []func(context.Context) error
 	}
 
 	Server struct {
@@ -66,14 +80,6 @@ type (
 		Secure bool
 		URL    string
 	}
-	config struct {
-		port        string
-		renderer    string // Template rendering
-		cookie      cookieConfig
-		sessionType string
-		database    databaseConfig
-		redis       redisConfig
-	}
 )
 
 func (u *Ugo) New(rootPath string) error {
@@ -90,6 +96,7 @@ func (u *Ugo) New(rootPath string) error {
 			"tmp",
 			"logs",
 			"middleware",
+			"lang",
 		},
 	}
 
@@ -99,46 +106,58 @@ func (u *Ugo) New(rootPath string) error {
 		return err
 	}
 
-	// Read and load e.nv
+	// Read and load .env and the rest of the configuration
 	err = u.checkDotEnv(rootPath)
 	if err != nil {
 		return err
 	}
-	err = godotenv.Load(rootPath + "/" + envFile)
+	cfg, err := LoadConfig(rootPath)
 	if err != nil {
 		return err
 	}
+	u.Config = *cfg
 
 	// Init loggers
 	infoLog, errorLog := u.createLoggers()
+	u.InfoLog = infoLog
+	u.ErrorLog = errorLog
+
+	u.AppName = u.Config.AppName
+	u.Debug = u.Config.Debug
+	u.Version = version
+	u.RootPath = rootPath
 
 	//  init scheduler
 	u.Scheduler = cron.New()
 
 	// Connect to database
-	if os.Getenv("DATABASE_TYPE") != "" {
-		db, err := u.OpenDB(os.Getenv("DATABASE_TYPE"), u.BuildDSN())
+	if u.Config.Database.Type != "" {
+		db, err := u.OpenDB(u.Config.Database.Type, u.BuildDSN())
 		if err != nil {
 			errorLog.Printf("error connecting to database: %s\n", err.Error())
 			os.Exit(1)
 		}
+		u.configurePool(db)
 
 		u.DB = database{
-			DataType: os.Getenv("DATABASE_TYPE"),
+			DataType: u.Config.Database.Type,
 			Pool:     db,
 		}
 
+		err = u.openReplicas()
+		if err != nil {
+			errorLog.Printf("error connecting to database replica: %s\n", err.Error())
+			os.Exit(1)
+		}
 	}
 
-	if os.Getenv("CACHE") == "redis" || os.Getenv("SESSION_TYPE") == "redis" {
+	if u.Config.Cache == "redis" || u.Config.SessionType == "redis" {
 		redisCache = u.createClientRedisCache()
-		u.Cache = redisCache
 		redisPool = redisCache.Conn
 	}
 
-	if os.Getenv("CACHE") == "badger" || os.Getenv("SESSION_TYPE") == "badger" {
+	if u.Config.Cache == "badger" {
 		badgerCache = u.createClientBadgerCache()
-		u.Cache = badgerCache
 		badgerConn = badgerCache.Conn
 
 		_, err = u.Scheduler.AddFunc("@daily", func() {
@@ -149,83 +168,126 @@ func (u *Ugo) New(rootPath string) error {
 		}
 	}
 
-	u.InfoLog = infoLog
-	u.ErrorLog = errorLog
-	isDebug, err := strconv.ParseBool(os.Getenv(debug))
-	if err != nil {
-		return err
+	if u.Config.SessionType == "badger" {
+		sessionBadgerConn = u.createBadgerConn("badger-sessions")
+		if sessionBadgerConn == nil {
+			return errors.New("could not open the badger session store")
+		}
+
+		_, err = u.Scheduler.AddFunc("@daily", func() {
+			_ = sessionBadgerConn.RunValueLogGC(0.7)
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	u.Debug = isDebug
-	u.Version = version
-	u.RootPath = rootPath
-	u.Mail = u.createMailer()
-	u.config = config{
-		port:     os.Getenv(port),
-		renderer: os.Getenv(renderer),
-		cookie: cookieConfig{
-			name:     os.Getenv("COOKIE_NAME"),
-			lifetime: os.Getenv("COOKIE_LIFETIME"),
-			persist:  os.Getenv("COOKIE_PERSISTS"),
-			secure:   os.Getenv("COOKIE_SECURE"),
-			domain:   os.Getenv("COOKIE_DOMAIN"),
-		},
-		sessionType: os.Getenv("SESSION_TYPE"),
-		database: databaseConfig{
-			database: os.Getenv("DATABASE_TYPE"),
-			dsn:      u.BuildDSN(),
-		},
-		redis: redisConfig{
-			host:     os.Getenv("REDIS_HOST"),
-			password: os.Getenv("REDIS_PASSWORD"),
-			prefix:   os.Getenv("REDIS_PREFIX"),
-		},
+	switch u.Config.Cache {
+	case "redis":
+		u.Cache = redisCache
+	case "badger":
+		u.Cache = badgerCache
+	case "database":
+		databaseCache := &cache.DatabaseCache{
+			Conn:       u.DB.Pool,
+			DataType:   u.Config.Database.Type,
+			Serializer: u.cacheSerializer(),
+		}
+		u.Cache = databaseCache
+
+		_, err = u.Scheduler.AddFunc("@every 10m", func() {
+			if err := databaseCache.DeleteExpired(); err != nil {
+				u.ErrorLog.Println("error purging expired cache rows:", err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		memoryCache := cache.NewMemoryCache(u.Config.CacheMaxEntries, int64(u.Config.CacheMaxBytes))
+		u.Cache = memoryCache
+
+		_, err = u.Scheduler.AddFunc("@every 1m", memoryCache.DeleteExpired)
+		if err != nil {
+			return err
+		}
+	}
+
+	if u.Config.CacheMetrics != "" {
+		u.cacheMetrics = cache.NewInstrumentedCache(u.Cache)
+		// the view keeps the tags, counters and locks of the cache being instrumented
+		u.Cache = u.cacheMetrics.Cache()
 	}
 
+	u.Mail = u.createMailer()
+
 	secure := true
 	protocol := "https"
-	if strings.ToLower(os.Getenv("SECURE")) == "false" {
+	if !u.Config.Server.Secure && !u.tlsEnabled() {
 		secure = false
 		protocol = "http"
 	}
 
 	u.Server = Server{
-		Name:   os.Getenv("SERVER_NAME"),
-		Port:   os.Getenv("PORT"),
+		Name:   u.Config.Server.Name,
+		Port:   u.Config.Server.Port,
 		Secure: secure,
-		URL:    fmt.Sprintf("%s://%s:%s", protocol, os.Getenv("SERVER_NAME"), os.Getenv("PORT")),
+		URL:    fmt.Sprintf("%s://%s:%s", protocol, u.Config.Server.Name, u.Config.Server.Port),
 	}
 
 	// inject session
 	sess := session.Session{
-		CookieLifetime: u.config.cookie.lifetime,
-		CookiePersist:  u.config.cookie.persist,
-		CookieName:     u.config.cookie.name,
-		CookieDomain:   u.config.cookie.domain,
-		SessionType:    u.config.sessionType,
-		CookieSecure:   u.config.cookie.secure,
-	}
-	switch u.config.sessionType {
+		CookieLifetime: strconv.Itoa(int(u.Config.Cookie.sessionLifetime().Minutes())),
+		IdleTimeout:    strconv.Itoa(int(u.Config.Cookie.IdleTimeout.Minutes())),
+		CookiePersist:  strconv.FormatBool(u.Config.Cookie.Persist),
+		CookieName:     u.Config.Cookie.Name,
+		CookieDomain:   u.Config.Cookie.Domain,
+		SessionType:    u.Config.SessionType,
+		CookieSecure:   strconv.FormatBool(u.Config.Cookie.Secure),
+		CookieSameSite: u.Config.Cookie.SameSite,
+		EncryptionKeys: append([]string{u.Config.Key}, u.Config.PreviousKeys...),
+	}
+	switch u.Config.SessionType {
 	case "redis":
 		sess.RedisPool = redisCache.Conn
-	case "mysql", "postgres", "mariadb", "postgresql":
+	case "badger":
+		sess.BadgerConn = sessionBadgerConn
+	case "mysql", "postgres", "mariadb", "postgresql", "sqlite":
 		sess.DBPool = u.DB.Pool
 	}
 
 	u.Session = sess.InitSession()
-	u.EncryptionKey = os.Getenv("KEY")
+	u.EncryptionKey = u.Config.Key
+	u.Sessions = session.NewRegistry(u.Session, []byte(u.EncryptionKey))
+
+	var langs fs.FS = os.DirFS(rootPath + "/lang")
+	if sub := u.subFS("lang"); sub != nil {
+		langs = sub
+	}
+	u.I18n, err = i18n.Load(langs, u.Config.Locale.Default)
+	if err != nil {
+		return err
+	}
 
 	u.Routes = u.routes().(*chi.Mux)
 
+	var loader jet.Loader = jet.NewOSFileSystemLoader(fmt.Sprintf("%s/views", rootPath))
+	if views := u.subFS("views"); views != nil {
+		loader, err = httpfs.NewLoader(http.FS(views))
+		if err != nil {
+			return err
+		}
+	}
+
 	if u.Debug {
 		var views = jet.NewSet(
-			jet.NewOSFileSystemLoader(fmt.Sprintf("%s/views", rootPath)),
+			loader,
 			jet.InDevelopmentMode(),
 		)
 		u.JetViews = views
 	} else {
 		var views = jet.NewSet(
-			jet.NewOSFileSystemLoader(fmt.Sprintf("%s/views", rootPath)),
+			loader,
 		)
 		u.JetViews = views
 	}
@@ -252,33 +314,71 @@ func (u *Ugo) Init(p initPaths) error {
 	return nil
 }
 
-// ListenAndServe starts the web server
+// ListenAndServe starts the scheduler and the web server, over TLS with HTTP/2 when TLS_CERT and TLS_KEY are set, and blocks until it receives
+// SIGINT or SIGTERM, at which point in-flight requests are drained and the application is shut down
 func (u *Ugo) ListenAndServe() {
 	srv := &http.Server{
-		Addr:         fmt.Sprintf("%s:%s", u.Server.Name, u.config.port),
+		Addr:         fmt.Sprintf("%s:%s", u.Server.Name, u.Config.Server.Port),
 		ErrorLog:     u.ErrorLog,
-		Handler:      u.Routes,
+		Handler:      u.handler(),
 		IdleTimeout:  30 * time.Second,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 600 * time.Second,
 	}
 
-	if u.DB.Pool != nil {
-		defer u.DB.Pool.Close()
+	servers := []*http.Server{srv}
+
+	if u.tlsEnabled() {
+		if err := u.configureTLS(srv); err != nil {
+			u.ErrorLog.Panicln(err)
+		}
+
+		if u.Config.Server.TLSRedirectPort != "" {
+			servers = append(servers, u.redirectServer())
+		}
 	}
 
-	if redisPool != nil {
-		defer redisPool.Close()
+	if err := u.start(); err != nil {
+		u.ErrorLog.Panicln(err)
 	}
 
-	if badgerConn != nil {
-		defer badgerConn.Close()
+	// the jobs that purge expired cache entries and collect badger garbage, and any the
+	// application added, only run once the scheduler is started. Starting it twice is harmless
+	u.Scheduler.Start()
+
+	serverErr := make(chan error, len(servers))
+	go func() {
+		u.InfoLog.Printf("Listening on %s:%s: Debug: %t TLS: %t\n", u.Server.Name, u.Config.Server.Port, u.Debug, u.tlsEnabled())
+		if u.tlsEnabled() {
+			serverErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- srv.ListenAndServe()
+		}
+	}()
+
+	for _, redirect := range servers[1:] {
+		go func(redirect *http.Server) {
+			u.InfoLog.Printf("Redirecting HTTP on %s to HTTPS\n", redirect.Addr)
+			serverErr <- redirect.ListenAndServe()
+		}(redirect)
 	}
 
-	u.InfoLog.Printf("Listening on %s:%s: Debug: %t\n", u.Server.Name, u.config.port, u.Debug)
-	err := srv.ListenAndServe()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
 
-	if err != nil {
+	var err error
+	select {
+	case err = <-serverErr:
+	case sig := <-quit:
+		u.InfoLog.Printf("Received %s, shutting down\n", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), u.Config.ShutdownTimeout)
+	defer cancel()
+	u.shutdown(ctx, servers...)
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		u.ErrorLog.Panicln(err)
 	}
 }
@@ -307,52 +407,116 @@ func (u *Ugo) createLoggers() (*log.Logger, *log.Logger) {
 func (u *Ugo) createRenderer() {
 
 	u.Render = &render.Render{
-		Renderer: u.config.renderer,
+		Renderer: u.Config.Renderer,
 		RootPath: u.RootPath,
-		Port:     u.config.port,
+		Port:     u.Config.Server.Port,
 		JetViews: u.JetViews,
 		Session:  u.Session,
+		Debug:    u.Debug,
+		Views:    u.subFS("views"),
 	}
 
 }
 
-func (u *Ugo) createMailer() mailer.Mail {
+// subFS returns the folder dir of FS, or nil when the application is read from disk
+func (u *Ugo) subFS(dir string) fs.FS {
+	if u.FS == nil {
+		return nil
+	}
 
-	port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	// Sub only fails for invalid names, and dir is always one of ours
+	sub, _ := fs.Sub(u.FS, dir)
+	return sub
+}
 
+// Public serves the public folder, from FS when it is set
+func (u *Ugo) Public() http.Handler {
+	if public := u.subFS("public"); public != nil {
+		return http.FileServer(http.FS(public))
+	}
+	return http.FileServer(http.Dir(u.RootPath + "/public"))
+}
+
+func (u *Ugo) createMailer() mailer.Mail {
 	return mailer.Mail{
-		Domain:      os.Getenv("MAIL_DOMAIN"),
+		Domain:      u.Config.Mail.Domain,
 		Templates:   u.RootPath + "/mail",
-		Host:        os.Getenv("SMTP_HOST"),
-		Port:        port,
-		Username:    os.Getenv("SMTP_USERNAME"),
-		Password:    os.Getenv("SMTP_PASSWORD"),
-		Encryption:  os.Getenv("SMTP_ENCRYPTION"),
-		FromName:    os.Getenv("FROM_NAME"),
-		FromAddress: os.Getenv("FROM_ADDRESS"),
+		FS:          u.subFS("mail"),
+		Host:        u.Config.Mail.Host,
+		Port:        u.Config.Mail.Port,
+		Username:    u.Config.Mail.Username,
+		Password:    u.Config.Mail.Password,
+		Encryption:  u.Config.Mail.Encryption,
+		FromName:    u.Config.Mail.FromName,
+		FromAddress: u.Config.Mail.FromAddress,
 		Jobs:        make(chan mailer.Message, 20),
 		Result:      make(chan mailer.Result, 20),
-		API:         os.Getenv("MAILER_API"),
-		APIKey:      os.Getenv("MAILER_KEY"),
-		APIURL:      os.Getenv("MAILER_URL"),
+		Done:        make(chan struct{}),
+		API:         u.Config.Mail.API,
+		APIKey:      u.Config.Mail.APIKey,
+		APIURL:      u.Config.Mail.APIURL,
 	}
 }
 
 func (u *Ugo) createClientRedisCache() *cache.RedisCache {
 	return &cache.RedisCache{
-		Conn:   u.createRedisPool(),
-		Prefix: u.config.redis.prefix,
+		Conn:       u.createRedisPool(),
+		Prefix:     u.Config.Redis.Prefix,
+		Serializer: u.cacheSerializer(),
 	}
 }
 
 func (u *Ugo) createClientBadgerCache() *cache.BadgerCache {
 	return &cache.BadgerCache{
-		Conn: u.createBadgerConn(),
+		Conn:       u.createBadgerConn("badger"),
+		Serializer: u.cacheSerializer(),
+	}
+}
+
+// cacheSerializer returns the codec and compression configured for the cache. The names
+// have already been checked by LoadConfig, so lookups cannot fail
+func (u *Ugo) cacheSerializer() *cache.Serializer {
+	codec, _ := cache.CodecByName(u.Config.CacheCodec)
+	compressor, _ := cache.CompressorByName(u.Config.CacheCompress)
+
+	return &cache.Serializer{
+		Codec:      codec,
+		Compressor: compressor,
+		Threshold:  u.Config.CacheCompressAt,
 	}
 }
 
-func (u *Ugo) createBadgerConn() *badger.DB {
-	db, err := badger.Open(badger.DefaultOptions(u.RootPath + "tmp/badger"))
+// OpenCache connects to the cache store named by CACHE from outside a running application,
+// for command line tools. The memory cache only exists inside the application, so it cannot
+// be opened. The returned function closes the connection
+func (u *Ugo) OpenCache() (cache.Cache, func() error, error) {
+	switch u.Config.Cache {
+	case "redis":
+		c := u.createClientRedisCache()
+		return c, c.Conn.Close, nil
+
+	case "badger":
+		db, err := badger.Open(badger.DefaultOptions(u.RootPath + "/tmp/badger").WithLogger(nil))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &cache.BadgerCache{Conn: db, Serializer: u.cacheSerializer()}, db.Close, nil
+
+	case "database":
+		db, err := u.OpenDB(u.Config.Database.Type, u.BuildDSN())
+		if err != nil {
+			return nil, nil, err
+		}
+		c := &cache.DatabaseCache{Conn: db, DataType: u.Config.Database.Type, Serializer: u.cacheSerializer()}
+		return c, db.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("the %s cache only exists inside the running application", u.Config.Cache)
+}
+
+// createBadgerConn opens the badger database in tmp/<dir>
+func (u *Ugo) createBadgerConn(dir string) *badger.DB {
+	db, err := badger.Open(badger.DefaultOptions(u.RootPath + "/tmp/" + dir))
 	if err != nil {
 		u.ErrorLog.Println(err)
 		return nil
@@ -366,7 +530,7 @@ func (u *Ugo) createRedisPool() *redis.Pool {
 		MaxActive:   10000,
 		IdleTimeout: 240 * time.Second,
 		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", u.config.redis.host, redis.DialPassword(u.config.redis.password))
+			return redis.Dial("tcp", u.Config.Redis.Host, redis.DialPassword(u.Config.Redis.Password))
 		},
 
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
@@ -378,27 +542,84 @@ func (u *Ugo) createRedisPool() *redis.Pool {
 
 // BuildDSN build sql connection string
 func (u *Ugo) BuildDSN() string {
+	return u.buildDSN(u.Config.Database)
+}
+
+// buildDSN builds the connection string for db, which is the primary database or one of its replicas
+func (u *Ugo) buildDSN(db DatabaseConfig) string {
 	var dsn string
 
-	switch os.Getenv("DATABASE_TYPE") {
+	switch db.Type {
 	case "postgres", "postgresql":
-		dsn = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
-			os.Getenv("DATABASE_HOST"),
-			os.Getenv("DATABASE_PORT"),
-			os.Getenv("DATABASE_USER"),
-			os.Getenv("DATABASE_NAME"),
-			os.Getenv("DATABASE_SSL_MODE"),
+		dsn = fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s timezone=%s connect_timeout=5",
+			db.Host,
+			db.Port,
+			db.User,
+			db.Name,
+			db.SSLMode,
+			db.Timezone,
 		)
 
-		if os.Getenv("DATABASE_PASS") != "" {
-			dsn = fmt.Sprintf("%s password=%s", dsn, os.Getenv("DATABASE_PASS"))
+		if db.Pass != "" {
+			dsn = fmt.Sprintf("%s password=%s", dsn, db.Pass)
 		}
+	case "mysql", "mariadb":
+		dsn = u.buildMySQLDSN(db)
+	case "sqlite":
+		dsn = u.buildSQLiteDSN(db)
+	}
+
+	return dsn
+}
+
+// buildMySQLDSN builds a go-sql-driver/mysql connection string. DATABASE_SSL_MODE accepts the
+// postgres style modes (disable, require, verify-full) as well as the driver's own tls values
+func (u *Ugo) buildMySQLDSN(db DatabaseConfig) string {
+
+	port := db.Port
+	if port == "" {
+		port = "3306"
+	}
+
+	loc, err := time.LoadLocation(db.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = db.User
+	cfg.Passwd = db.Pass
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(db.Host, port)
+	cfg.DBName = db.Name
+	cfg.ParseTime = true
+	cfg.Collation = db.Collation
+	cfg.Loc = loc
+	cfg.Timeout = 5 * time.Second
+
+	switch strings.ToLower(db.SSLMode) {
+	case "", "disable", "false":
+		cfg.TLSConfig = "false"
+	case "require", "skip-verify":
+		cfg.TLSConfig = "skip-verify"
+	case "prefer", "preferred":
+		cfg.TLSConfig = "preferred"
 	default:
-		// i will be back
+		cfg.TLSConfig = "true"
+	}
 
+	return cfg.FormatDSN()
+}
+
+// buildSQLiteDSN builds a connection string for the database file in DATABASE_NAME, which is
+// relative to the application root unless it is an absolute path
+func (u *Ugo) buildSQLiteDSN(db DatabaseConfig) string {
+	path := db.Name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(u.RootPath, path)
 	}
 
-	return dsn
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
 }
 
 func (u *Ugo) GenerateRandomString(n int) string {
@@ -0,0 +1,64 @@
+package ugo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBuildMySQLDSN(t *testing.T) {
+	tests := []struct {
+		name      string
+		db        DatabaseConfig
+		addr      string
+		tls       string
+		collation string
+		loc       string
+	}{
+		{"defaults", DatabaseConfig{}, "db:3306", "false", "utf8mb4_general_ci", "UTC"},
+		{"disable", DatabaseConfig{SSLMode: "disable"}, "db:3306", "false", "utf8mb4_general_ci", "UTC"},
+		{"require", DatabaseConfig{SSLMode: "require"}, "db:3306", "skip-verify", "utf8mb4_general_ci", "UTC"},
+		{"skip_verify", DatabaseConfig{SSLMode: "skip-verify"}, "db:3306", "skip-verify", "utf8mb4_general_ci", "UTC"},
+		{"prefer", DatabaseConfig{SSLMode: "Prefer"}, "db:3306", "preferred", "utf8mb4_general_ci", "UTC"},
+		{"verify_full", DatabaseConfig{SSLMode: "verify-full"}, "db:3306", "true", "utf8mb4_general_ci", "UTC"},
+		{"port", DatabaseConfig{Port: "3307"}, "db:3307", "false", "utf8mb4_general_ci", "UTC"},
+		{"collation", DatabaseConfig{Collation: "utf8mb4_unicode_ci"}, "db:3306", "false", "utf8mb4_unicode_ci", "UTC"},
+		{"timezone", DatabaseConfig{Timezone: "Africa/Lagos"}, "db:3306", "false", "utf8mb4_general_ci", "Africa/Lagos"},
+		{"unknown_timezone", DatabaseConfig{Timezone: "Mars/Olympus"}, "db:3306", "false", "utf8mb4_general_ci", "UTC"},
+	}
+
+	u := &Ugo{}
+	for _, tt := range tests {
+		db := tt.db
+		db.Host, db.User, db.Pass, db.Name = "db", "app", "p@ss:word/", "shop"
+		if db.Collation == "" {
+			db.Collation = "utf8mb4_general_ci"
+		}
+
+		cfg, err := mysql.ParseDSN(u.buildMySQLDSN(db))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+
+		if cfg.User != "app" || cfg.Passwd != "p@ss:word/" || cfg.DBName != "shop" || cfg.Addr != tt.addr {
+			t.Errorf("%s: unexpected connection %s:%s@%s/%s", tt.name, cfg.User, cfg.Passwd, cfg.Addr, cfg.DBName)
+		}
+		if cfg.TLSConfig != tt.tls {
+			t.Errorf("%s: expected tls=%s; got %s", tt.name, tt.tls, cfg.TLSConfig)
+		}
+		if !cfg.ParseTime {
+			t.Errorf("%s: expected parseTime", tt.name)
+		}
+		if cfg.Collation != tt.collation {
+			t.Errorf("%s: expected collation %s; got %s", tt.name, tt.collation, cfg.Collation)
+		}
+		if cfg.Loc.String() != tt.loc {
+			t.Errorf("%s: expected loc %s; got %s", tt.name, tt.loc, cfg.Loc)
+		}
+		if cfg.Timeout != 5*time.Second {
+			t.Errorf("%s: expected a timeout of 5s; got %s", tt.name, cfg.Timeout)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ugo
 
 import (
 	"github.com/asaskevich/govalidator"
+	"github.com/joefazee/ugo/i18n"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -12,14 +13,32 @@ import (
 type Validation struct {
 	Data   url.Values
 	Errors map[string]string
+	// Translator translates the messages of the checks, from the validation keys of the
+	// catalogs such as validation.required. Messages it has no translation for stay in English
+	Translator *i18n.Translator
 }
 
+// Validator returns a validation with messages in the default language
 func (u *Ugo) Validator(data url.Values) *Validation {
 
-	return &Validation{
+	v := &Validation{
 		Errors: make(map[string]string),
 		Data:   data,
 	}
+	if u.I18n != nil {
+		v.Translator = u.I18n.Translator(u.I18n.Default)
+	}
+
+	return v
+}
+
+// ValidatorFor returns a validation with messages in the language of r
+func (u *Ugo) ValidatorFor(r *http.Request, data url.Values) *Validation {
+	v := u.Validator(data)
+	if tr := i18n.FromContext(r.Context()); tr != nil {
+		v.Translator = tr
+	}
+	return v
 }
 
 func (v *Validation) Valid() bool {
@@ -32,6 +51,14 @@ func (v *Validation) AddError(key, msg string) {
 	}
 }
 
+// message translates key, where {field} is the name of the field, or returns fallback
+func (v *Validation) message(key, field, fallback string) string {
+	if msg, ok := v.Translator.Lookup(key, "field", field); ok {
+		return msg
+	}
+	return fallback
+}
+
 func (v *Validation) Has(field string, r *http.Request) bool {
 	return r.Form.Get(field) != ""
 }
@@ -40,7 +67,7 @@ func (v *Validation) Required(r *http.Request, fields ...string) {
 	for _, f := range fields {
 		val := r.Form.Get(f)
 		if strings.TrimSpace(val) == "" {
-			v.AddError(f, "this field cannot be blank")
+			v.AddError(f, v.message("validation.required", f, "this field cannot be blank"))
 		}
 	}
 }
@@ -53,30 +80,30 @@ func (v *Validation) Check(ok bool, key, message string) {
 
 func (v *Validation) IsEmail(field, value string) {
 	if !govalidator.IsEmail(value) {
-		v.AddError(field, "invalid email address")
+		v.AddError(field, v.message("validation.email", field, "invalid email address"))
 	}
 }
 
 func (v *Validation) IsInt(field, value string) {
 	if _, err := strconv.Atoi(value); err != nil {
-		v.AddError(field, "This field must be an integer")
+		v.AddError(field, v.message("validation.int", field, "This field must be an integer"))
 	}
 }
 
 func (v *Validation) IsFloat(field, value string) {
 	if _, err := strconv.ParseFloat(value, 64); err != nil {
-		v.AddError(field, "This field must be a floating point number")
+		v.AddError(field, v.message("validation.float", field, "This field must be a floating point number"))
 	}
 }
 
 func (v *Validation) IsDateISO(field, value string) {
 	if _, err := time.Parse("2006-01-02", value); err != nil {
-		v.AddError(field, "must be a date in the form of YYYY-MM-DD")
+		v.AddError(field, v.message("validation.date", field, "must be a date in the form of YYYY-MM-DD"))
 	}
 }
 
 func (v *Validation) NoSpaces(field, value string) {
 	if !govalidator.HasWhitespace(value) {
-		v.AddError(field, "spaces are not allowed in this field")
+		v.AddError(field, v.message("validation.no_spaces", field, "spaces are not allowed in this field"))
 	}
 }